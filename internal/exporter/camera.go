@@ -0,0 +1,30 @@
+package exporter
+
+// enabled reports whether an ipcam setting is "enable". ok is false when the
+// setting is missing from the report.
+func enabled(setting string) (value float64, ok bool) {
+	switch setting {
+	case "enable":
+		return 1, true
+	case "disable":
+		return 0, true
+	}
+	return 0, false
+}
+
+// updateCamera exports the recording, timelapse and resolution settings of
+// the built-in camera.
+func (e *Exporter) updateCamera(data BambuLabsX1C) {
+	ipcam := data.Print.Ipcam
+
+	if value, ok := enabled(ipcam.IpcamRecord); ok {
+		e.cameraRecordingMetric.Set(value)
+	}
+	if value, ok := enabled(ipcam.Timelapse); ok {
+		e.timelapseEnabledMetric.Set(value)
+	}
+	if ipcam.Resolution != "" {
+		e.cameraResolutionMetric.Reset()
+		e.cameraResolutionMetric.WithLabelValues(ipcam.Resolution).Set(1)
+	}
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCameraMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"ipcam": {
+				"ipcam_dev": "1",
+				"ipcam_record": "enable",
+				"resolution": "1080p",
+				"timelapse": "disable"
+			}
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.cameraRecordingMetric); value != 1 {
+		t.Errorf("Expected camera recording 1, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.timelapseEnabledMetric); value != 0 {
+		t.Errorf("Expected timelapse disabled, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.cameraResolutionMetric.WithLabelValues("1080p")); value != 1 {
+		t.Errorf("Expected resolution 1080p, got %v", value)
+	}
+
+	// Reports without ipcam keep the previous values.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status"}}`)})
+
+	if value := testutil.ToFloat64(exporter.cameraRecordingMetric); value != 1 {
+		t.Errorf("Expected camera recording to stay 1, got %v", value)
+	}
+
+	report = `{"print": {"command": "push_status", "ipcam": {"ipcam_record": "disable", "resolution": "720p", "timelapse": "enable"}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.cameraRecordingMetric); value != 0 {
+		t.Errorf("Expected camera recording 0, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.timelapseEnabledMetric); value != 1 {
+		t.Errorf("Expected timelapse enabled, got %v", value)
+	}
+	if count := testutil.CollectAndCount(exporter.cameraResolutionMetric); count != 1 {
+		t.Errorf("Expected a single resolution series, got %d", count)
+	}
+}
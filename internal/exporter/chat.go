@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// chatStyles sets how each event type is presented in chat messages.
+var chatStyles = map[string]struct {
+	emoji    string
+	ntfyTag  string
+	color    int
+	priority string
+}{
+	EventJobStarted:  {"▶️", "arrow_forward", 0x3498DB, "default"},
+	EventJobFinished: {"✅", "white_check_mark", 0x2ECC71, "default"},
+	EventJobFailed:   {"❌", "x", 0xE74C3C, "high"},
+	EventJobPaused:   {"⏸️", "pause_button", 0xF1C40F, "default"},
+	EventHMSError:    {"⚠️", "warning", 0xE67E22, "high"},
+	EventFilamentLow: {"🧵", "thread", 0xF1C40F, "default"},
+}
+
+// chatMessage returns the title and body of a chat message for an event. The
+// body lists the job, progress, remaining time and any details of the event.
+func chatMessage(event Event) (title, body string) {
+	title = event.Message
+	if style, ok := chatStyles[event.Type]; ok {
+		title = style.emoji + " " + title
+	}
+
+	var lines []string
+	if event.Job != "" {
+		lines = append(lines, "Job: "+event.Job)
+	}
+	lines = append(lines, fmt.Sprintf("Progress: %.0f%%", event.Progress))
+	if event.RemainingSeconds > 0 && event.Type != EventJobFinished {
+		lines = append(lines, "Remaining: "+formatRemaining(event.RemainingSeconds))
+	}
+	if event.TotalLayers > 0 {
+		lines = append(lines, fmt.Sprintf("Layer: %d/%d", event.Layer, event.TotalLayers))
+	}
+	if event.HMSCode != "" {
+		lines = append(lines, "HMS: "+event.HMSCode)
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// formatRemaining formats a remaining time in seconds as e.g. 1h 5m.
+func formatRemaining(seconds float64) string {
+	remaining := time.Duration(seconds) * time.Second
+	hours, minutes := int(remaining.Hours()), int(remaining.Minutes())%60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
+
+// newChatNotifiers returns a notifier for each configured chat service. They
+// share the BAMBULABS_CHAT_EVENTS filter.
+func newChatNotifiers(cfg Config) []notifier {
+	var notifiers []notifier
+	if cfg.DiscordWebhookURL != "" {
+		notifiers = append(notifiers, &discord{eventFilter: cfg.ChatEvents, url: cfg.DiscordWebhookURL, client: &http.Client{}})
+	}
+	if cfg.SlackWebhookURL != "" {
+		notifiers = append(notifiers, &slack{eventFilter: cfg.ChatEvents, url: cfg.SlackWebhookURL, client: &http.Client{}})
+	}
+	if cfg.NtfyURL != "" {
+		notifiers = append(notifiers, &ntfy{eventFilter: cfg.ChatEvents, url: cfg.NtfyURL, token: cfg.NtfyToken, client: &http.Client{}})
+	}
+	if cfg.TelegramBotToken != "" {
+		notifiers = append(notifiers, &telegram{
+			eventFilter: cfg.ChatEvents,
+			url:         strings.TrimSuffix(cfg.TelegramAPIURL, "/") + "/bot" + cfg.TelegramBotToken + "/sendMessage",
+			chatID:      cfg.TelegramChatID,
+			client:      &http.Client{},
+		})
+	}
+	return notifiers
+}
+
+// postJSON marshals payload and posts it.
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{err}
+	}
+	return postNotification(ctx, client, url, "application/json", body, nil)
+}
+
+// discord posts events to a Discord webhook as an embed.
+type discord struct {
+	eventFilter
+	url    string
+	client *http.Client
+}
+
+func (d *discord) name() string {
+	return "discord"
+}
+
+func (d *discord) notify(ctx context.Context, event Event) error {
+	title, body := chatMessage(event)
+	embed := map[string]any{
+		"title":       title,
+		"description": body,
+		"timestamp":   event.Time.Format(time.RFC3339),
+	}
+	if style, ok := chatStyles[event.Type]; ok {
+		embed["color"] = style.color
+	}
+	return postJSON(ctx, d.client, d.url, map[string]any{"embeds": []any{embed}})
+}
+
+// slack posts events to a Slack incoming webhook.
+type slack struct {
+	eventFilter
+	url    string
+	client *http.Client
+}
+
+func (s *slack) name() string {
+	return "slack"
+}
+
+func (s *slack) notify(ctx context.Context, event Event) error {
+	title, body := chatMessage(event)
+	return postJSON(ctx, s.client, s.url, map[string]string{"text": "*" + title + "*\n" + body})
+}
+
+// ntfy publishes events to an ntfy topic URL, e.g. https://ntfy.sh/mytopic.
+type ntfy struct {
+	eventFilter
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (n *ntfy) name() string {
+	return "ntfy"
+}
+
+func (n *ntfy) notify(ctx context.Context, event Event) error {
+	// ntfy renders the tag as an emoji, so it is left out of the title.
+	_, body := chatMessage(event)
+	headers := map[string]string{"Title": event.Message}
+	if style, ok := chatStyles[event.Type]; ok {
+		headers["Tags"] = style.ntfyTag
+		headers["Priority"] = style.priority
+	}
+	if n.token != "" {
+		headers["Authorization"] = "Bearer " + n.token
+	}
+	return postNotification(ctx, n.client, n.url, "text/plain; charset=utf-8", []byte(body), headers)
+}
+
+// telegram sends events to a chat with the Telegram Bot API.
+type telegram struct {
+	eventFilter
+	url    string
+	chatID string
+	client *http.Client
+}
+
+func (t *telegram) name() string {
+	return "telegram"
+}
+
+func (t *telegram) notify(ctx context.Context, event Event) error {
+	title, body := chatMessage(event)
+	return postJSON(ctx, t.client, t.url, map[string]string{"chat_id": t.chatID, "text": title + "\n\n" + body})
+}
@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var failedEvent = Event{
+	Type:             EventJobFailed,
+	Time:             time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	Message:          "benchy failed",
+	Job:              "benchy",
+	Progress:         42,
+	RemainingSeconds: 3900,
+	Layer:            50,
+	TotalLayers:      120,
+}
+
+func TestChatMessage(t *testing.T) {
+	title, body := chatMessage(failedEvent)
+	if title != "❌ benchy failed" {
+		t.Errorf("Unexpected title: %s", title)
+	}
+	if expected := "Job: benchy\nProgress: 42%\nRemaining: 1h 5m\nLayer: 50/120"; body != expected {
+		t.Errorf("Expected body %q, got %q", expected, body)
+	}
+
+	_, body = chatMessage(Event{Type: EventHMSError, Message: "HMS error 0300_0100_0001_0007 on Print", HMSCode: "0300_0100_0001_0007"})
+	if expected := "Progress: 0%\nHMS: 0300_0100_0001_0007"; body != expected {
+		t.Errorf("Expected body %q, got %q", expected, body)
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	tests := []struct {
+		seconds  float64
+		expected string
+	}{
+		{300, "5m"},
+		{3600, "1h 0m"},
+		{3900, "1h 5m"},
+		{90000, "25h 0m"},
+	}
+
+	for _, tt := range tests {
+		if formatted := formatRemaining(tt.seconds); formatted != tt.expected {
+			t.Errorf("formatRemaining(%v) = %s, expected %s", tt.seconds, formatted, tt.expected)
+		}
+	}
+}
+
+func TestChatNotifiers(t *testing.T) {
+	discordServer, _, discordBodies := webhookReceiver(t, http.StatusNoContent)
+	slackServer, _, slackBodies := webhookReceiver(t, http.StatusOK)
+	ntfyServer, ntfyRequests, ntfyBodies := webhookReceiver(t, http.StatusOK)
+	telegramServer, telegramRequests, telegramBodies := webhookReceiver(t, http.StatusOK)
+
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_DISCORD_WEBHOOK_URL": discordServer.URL,
+		"BAMBULABS_SLACK_WEBHOOK_URL":   slackServer.URL,
+		"BAMBULABS_NTFY_URL":            ntfyServer.URL + "/printer",
+		"BAMBULABS_NTFY_TOKEN":          "tk_secret",
+		"BAMBULABS_TELEGRAM_BOT_TOKEN":  "123:abc",
+		"BAMBULABS_TELEGRAM_CHAT_ID":    "-100",
+		"BAMBULABS_TELEGRAM_API_URL":    telegramServer.URL,
+	})
+
+	// Started events are not sent to chats by default.
+	exporter.emit(Event{Type: EventJobStarted, Message: "benchy started"})
+	exporter.emit(failedEvent)
+	exporter.deliveries.Wait()
+
+	var discordPayload struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+		} `json:"embeds"`
+	}
+	if len(discordBodies) != 1 {
+		t.Fatalf("Expected 1 Discord message, got %d", len(discordBodies))
+	}
+	if err := json.Unmarshal(<-discordBodies, &discordPayload); err != nil {
+		t.Fatalf("Failed to unmarshal Discord payload: %v", err)
+	}
+	if len(discordPayload.Embeds) != 1 || discordPayload.Embeds[0].Title != "❌ benchy failed" || discordPayload.Embeds[0].Color != 0xE74C3C {
+		t.Errorf("Unexpected Discord payload: %+v", discordPayload)
+	}
+
+	var slackPayload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(<-slackBodies, &slackPayload); err != nil {
+		t.Fatalf("Failed to unmarshal Slack payload: %v", err)
+	}
+	if slackPayload.Text != "*❌ benchy failed*\nJob: benchy\nProgress: 42%\nRemaining: 1h 5m\nLayer: 50/120" {
+		t.Errorf("Unexpected Slack text: %q", slackPayload.Text)
+	}
+
+	ntfyRequest := <-ntfyRequests
+	if ntfyRequest.URL.Path != "/printer" {
+		t.Errorf("Expected ntfy topic /printer, got %s", ntfyRequest.URL.Path)
+	}
+	if title := ntfyRequest.Header.Get("Title"); title != "benchy failed" {
+		t.Errorf("Expected ntfy title 'benchy failed', got '%s'", title)
+	}
+	if tags, priority := ntfyRequest.Header.Get("Tags"), ntfyRequest.Header.Get("Priority"); tags != "x" || priority != "high" {
+		t.Errorf("Expected ntfy tag x with high priority, got %s %s", tags, priority)
+	}
+	if auth := ntfyRequest.Header.Get("Authorization"); auth != "Bearer tk_secret" {
+		t.Errorf("Expected ntfy token, got '%s'", auth)
+	}
+	if body := string(<-ntfyBodies); body != "Job: benchy\nProgress: 42%\nRemaining: 1h 5m\nLayer: 50/120" {
+		t.Errorf("Unexpected ntfy body: %q", body)
+	}
+
+	if path := (<-telegramRequests).URL.Path; path != "/bot123:abc/sendMessage" {
+		t.Errorf("Expected Telegram path /bot123:abc/sendMessage, got %s", path)
+	}
+	var telegramPayload struct {
+		ChatID string `json:"chat_id"`
+		Text   string `json:"text"`
+	}
+	if err := json.Unmarshal(<-telegramBodies, &telegramPayload); err != nil {
+		t.Fatalf("Failed to unmarshal Telegram payload: %v", err)
+	}
+	if telegramPayload.ChatID != "-100" || telegramPayload.Text != "❌ benchy failed\n\nJob: benchy\nProgress: 42%\nRemaining: 1h 5m\nLayer: 50/120" {
+		t.Errorf("Unexpected Telegram payload: %+v", telegramPayload)
+	}
+}
+
+// TestChatErrorsRedactSecrets checks that transport errors, which are logged,
+// do not include the secrets in the URLs of chat services.
+func TestChatErrorsRedactSecrets(t *testing.T) {
+	server, _, _ := webhookReceiver(t, http.StatusOK)
+	server.Close()
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		secret string
+	}{
+		{"discord", map[string]string{"BAMBULABS_DISCORD_WEBHOOK_URL": server.URL + "/api/webhooks/123/discordsecret"}, "discordsecret"},
+		{"slack", map[string]string{"BAMBULABS_SLACK_WEBHOOK_URL": server.URL + "/services/T0/B0/slacksecret"}, "slacksecret"},
+		{"ntfy", map[string]string{"BAMBULABS_NTFY_URL": server.URL + "/ntfysecret"}, "ntfysecret"},
+		{"telegram", map[string]string{
+			"BAMBULABS_TELEGRAM_BOT_TOKEN": "123:abc",
+			"BAMBULABS_TELEGRAM_CHAT_ID":   "-100",
+			"BAMBULABS_TELEGRAM_API_URL":   server.URL,
+		}, "123:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := newTestExporter(t, tt.env)
+			err := exporter.notifiers[0].notify(context.Background(), failedEvent)
+			if err == nil {
+				t.Fatal("Expected an error for a closed server")
+			}
+			if strings.Contains(err.Error(), tt.secret) || !strings.Contains(err.Error(), "REDACTED") {
+				t.Errorf("Expected the secret to be redacted, got %s", err)
+			}
+		})
+	}
+}
+
+func TestChatNotifiersDisabled(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	if len(exporter.notifiers) != 0 {
+		t.Errorf("Expected no notifiers by default, got %d", len(exporter.notifiers))
+	}
+}
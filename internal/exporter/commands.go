@@ -0,0 +1,232 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// commandHandler handles one message type. It receives the payload with only
+// the section holding the command, so each handler can decode it into its
+// own struct and decode errors in other sections are not counted twice.
+type commandHandler func(e *Exporter, payload []byte)
+
+// commandHandlers maps a message type to its handler. Message types are named
+// after the section of the payload holding the command and the command
+// itself, e.g. print.push_status for {"print": {"command": "push_status"}}.
+// Supporting a new firmware message only needs a struct, a handler and an
+// entry here.
+var commandHandlers = map[string]commandHandler{
+	"print.push_status":          handle((*Exporter).updateStatus),
+	"info.get_version":           handle((*Exporter).updateModuleInfo),
+	"print.project_file":         handle((*Exporter).handleProjectFile),
+	"print.gcode_line":           handle((*Exporter).handleGcodeLine),
+	"print.extrusion_cali":       handle((*Exporter).handleExtrusionCali),
+	"print.extrusion_cali_get":   handle((*Exporter).handleExtrusionCaliGet),
+	"print.ams_filament_setting": handle((*Exporter).handleAmsFilamentSetting),
+	"system.*":                   handle((*Exporter).handleSystem),
+	// Replies to the commands sent by the control API carry nothing to
+	// export. ledctrl replies are covered by system.*.
+	"print.pause":       ignore,
+	"print.resume":      ignore,
+	"print.stop":        ignore,
+	"print.print_speed": ignore,
+}
+
+// ignore handles messages that are expected but carry nothing to export.
+func ignore(e *Exporter, payload []byte) {}
+
+// handle returns a commandHandler that decodes the payload into T before
+// calling fn.
+func handle[T any](fn func(e *Exporter, msg T)) commandHandler {
+	return func(e *Exporter, payload []byte) {
+		if msg, ok := decodeMessage[T](e, payload); ok {
+			fn(e, msg)
+		}
+	}
+}
+
+// dispatch passes a message to the handlers of the commands it contains.
+// Commands without a handler are counted in
+// bambulabs_unknown_commands_total.
+func (e *Exporter) dispatch(payload []byte) {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &sections); err != nil {
+		fmt.Printf("Error unmarshalling JSON: %s\n", err)
+		return
+	}
+
+	for _, section := range slices.Sorted(maps.Keys(sections)) {
+		var header struct {
+			Command string `json:"command"`
+		}
+		if json.Unmarshal(sections[section], &header) != nil || header.Command == "" {
+			continue
+		}
+
+		command := section + "." + header.Command
+		handler, ok := commandHandlers[command]
+		if !ok {
+			handler, ok = commandHandlers[section+".*"]
+		}
+		if !ok {
+			fmt.Printf("Ignoring command: %s\n", command)
+			e.unknownCommandsMetric.WithLabelValues(command).Inc()
+			continue
+		}
+		handler(e, sectionPayload(section, sections[section]))
+	}
+}
+
+// sectionPayload returns a payload with only the given section.
+func sectionPayload(section string, raw json.RawMessage) []byte {
+	payload, _ := json.Marshal(map[string]json.RawMessage{section: raw})
+	return payload
+}
+
+// countResult counts the reply to a command by its result.
+func (e *Exporter) countResult(command, result string) {
+	if result == "" {
+		return
+	}
+	e.commandResultsMetric.WithLabelValues(command, result).Inc()
+}
+
+// projectFileReport is the reply to project_file, sent when a print is
+// started from a 3MF project.
+type projectFileReport struct {
+	Print struct {
+		Command     string `json:"command"`
+		SequenceID  string `json:"sequence_id"`
+		Param       string `json:"param"`
+		SubtaskName string `json:"subtask_name"`
+		ProjectID   string `json:"project_id"`
+		TaskID      string `json:"task_id"`
+		Result      string `json:"result"`
+		Reason      string `json:"reason"`
+	} `json:"print"`
+}
+
+func (e *Exporter) handleProjectFile(msg projectFileReport) {
+	fmt.Printf("Project file %s (%s): %s %s\n", msg.Print.SubtaskName, msg.Print.Param, msg.Print.Result, msg.Print.Reason)
+	e.countResult("print."+msg.Print.Command, msg.Print.Result)
+}
+
+// gcodeLineReport is the reply to gcode_line, which runs raw G-code.
+type gcodeLineReport struct {
+	Print struct {
+		Command    string `json:"command"`
+		SequenceID string `json:"sequence_id"`
+		Param      string `json:"param"`
+		Result     string `json:"result"`
+		Reason     string `json:"reason"`
+	} `json:"print"`
+}
+
+func (e *Exporter) handleGcodeLine(msg gcodeLineReport) {
+	e.countResult("print."+msg.Print.Command, msg.Print.Result)
+}
+
+// extrusionCaliReport is the reply to extrusion_cali, which starts a flow
+// dynamics calibration.
+type extrusionCaliReport struct {
+	Print struct {
+		Command        string `json:"command"`
+		SequenceID     string `json:"sequence_id"`
+		NozzleDiameter string `json:"nozzle_diameter"`
+		Result         string `json:"result"`
+		Reason         string `json:"reason"`
+	} `json:"print"`
+}
+
+func (e *Exporter) handleExtrusionCali(msg extrusionCaliReport) {
+	e.countResult("print."+msg.Print.Command, msg.Print.Result)
+}
+
+// extrusionCaliGetReport is the reply to extrusion_cali_get and lists the
+// stored flow dynamics calibrations.
+type extrusionCaliGetReport struct {
+	Print struct {
+		Command        string `json:"command"`
+		SequenceID     string `json:"sequence_id"`
+		NozzleDiameter string `json:"nozzle_diameter"`
+		Filaments      []struct {
+			CaliIdx        Number `json:"cali_idx"`
+			FilamentID     string `json:"filament_id"`
+			KValue         Number `json:"k_value"`
+			NCoef          Number `json:"n_coef"`
+			Name           string `json:"name"`
+			NozzleDiameter string `json:"nozzle_diameter"`
+			SettingID      string `json:"setting_id"`
+		} `json:"filaments"`
+		Result string `json:"result"`
+		Reason string `json:"reason"`
+	} `json:"print"`
+}
+
+// handleExtrusionCaliGet replaces the pressure advance series with the
+// calibrations in the reply.
+func (e *Exporter) handleExtrusionCaliGet(msg extrusionCaliGetReport) {
+	e.countResult("print."+msg.Print.Command, msg.Print.Result)
+	if msg.Print.Result != "" && msg.Print.Result != "success" {
+		return
+	}
+
+	e.pressureAdvanceMetric.Reset()
+	for _, filament := range msg.Print.Filaments {
+		e.pressureAdvanceMetric.WithLabelValues(filament.FilamentID, filament.Name, filament.NozzleDiameter).Set(filament.KValue.Float64())
+	}
+}
+
+// amsFilamentSettingReport is the reply to ams_filament_setting, sent when
+// the filament of an AMS tray is changed.
+type amsFilamentSettingReport struct {
+	Print struct {
+		Command       string `json:"command"`
+		SequenceID    string `json:"sequence_id"`
+		AmsID         Number `json:"ams_id"`
+		TrayID        Number `json:"tray_id"`
+		TrayInfoIdx   string `json:"tray_info_idx"`
+		TrayColor     string `json:"tray_color"`
+		TrayType      string `json:"tray_type"`
+		NozzleTempMin Number `json:"nozzle_temp_min"`
+		NozzleTempMax Number `json:"nozzle_temp_max"`
+		Result        string `json:"result"`
+		Reason        string `json:"reason"`
+	} `json:"print"`
+}
+
+// externalSpoolAmsID is the ams_id of the external spool holder, which is
+// not exported as an AMS tray.
+const externalSpoolAmsID = 255
+
+// handleAmsFilamentSetting updates the tray type and color right away
+// instead of waiting for the next full push_status report.
+func (e *Exporter) handleAmsFilamentSetting(msg amsFilamentSettingReport) {
+	e.countResult("print."+msg.Print.Command, msg.Print.Result)
+	if msg.Print.Result != "" && msg.Print.Result != "success" {
+		return
+	}
+	if !msg.Print.AmsID.Present() || !msg.Print.TrayID.Present() || msg.Print.AmsID.Int() == externalSpoolAmsID {
+		return
+	}
+
+	e.setTrayFilament(strconv.Itoa(msg.Print.AmsID.Int()), strconv.Itoa(msg.Print.TrayID.Int()), msg.Print.TrayType, msg.Print.TrayColor)
+}
+
+// systemReport is the reply to any command in the system section, e.g.
+// ledctrl.
+type systemReport struct {
+	System struct {
+		Command    string `json:"command"`
+		SequenceID string `json:"sequence_id"`
+		Result     string `json:"result"`
+		Reason     string `json:"reason"`
+	} `json:"system"`
+}
+
+func (e *Exporter) handleSystem(msg systemReport) {
+	e.countResult("system."+msg.System.Command, msg.System.Result)
+}
@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDispatchUnknownCommand(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "wrong_command", "layer_num": 10}}`)})
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"info": {"command": "get_firmware"}}`)})
+
+	if value := testutil.ToFloat64(exporter.unknownCommandsMetric.WithLabelValues("print.wrong_command")); value != 1 {
+		t.Errorf("Expected 1 unknown print.wrong_command, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.unknownCommandsMetric.WithLabelValues("info.get_firmware")); value != 1 {
+		t.Errorf("Expected 1 unknown info.get_firmware, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.layerNumberMetric); value != 0 {
+		t.Errorf("Expected unknown commands to be ignored, got layer number %v", value)
+	}
+}
+
+func TestDispatchControlReplies(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	for _, command := range []string{"print.pause", "print.resume", "print.stop", "print.print_speed"} {
+		section, name, _ := strings.Cut(command, ".")
+		payload := fmt.Sprintf(`{%q: {"command": %q, "sequence_id": "1", "result": "success"}}`, section, name)
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(payload)})
+
+		if value := testutil.ToFloat64(exporter.unknownCommandsMetric.WithLabelValues(command)); value != 0 {
+			t.Errorf("Expected the reply to %s not to be counted as unknown, got %v", command, value)
+		}
+	}
+}
+
+func TestCommandResults(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	for _, reply := range []string{
+		`{"print": {"command": "project_file", "param": "Metadata/plate_1.gcode", "subtask_name": "benchy", "result": "success"}}`,
+		`{"print": {"command": "gcode_line", "param": "G28", "result": "success"}}`,
+		`{"print": {"command": "gcode_line", "param": "G29", "result": "failed"}}`,
+		`{"print": {"command": "extrusion_cali", "nozzle_diameter": "0.4", "result": "success"}}`,
+		`{"system": {"command": "ledctrl", "led_node": "chamber_light", "result": "success"}}`,
+	} {
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+	}
+
+	expected := map[[2]string]float64{
+		{"print.project_file", "success"}:   1,
+		{"print.gcode_line", "success"}:     1,
+		{"print.gcode_line", "failed"}:      1,
+		{"print.extrusion_cali", "success"}: 1,
+		{"system.ledctrl", "success"}:       1,
+	}
+	for labels, want := range expected {
+		if value := testutil.ToFloat64(exporter.commandResultsMetric.WithLabelValues(labels[0], labels[1])); value != want {
+			t.Errorf("Expected %v %s replies with result %s, got %v", want, labels[0], labels[1], value)
+		}
+	}
+}
+
+func TestExtrusionCaliGet(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	reply := `{
+		"print": {
+			"command": "extrusion_cali_get",
+			"nozzle_diameter": "0.4",
+			"filaments": [
+				{"cali_idx": 1, "filament_id": "GFA00", "k_value": "0.020", "n_coef": "1.40", "name": "Bambu PLA Basic", "nozzle_diameter": "0.4"},
+				{"cali_idx": 2, "filament_id": "GFG00", "k_value": 0.035, "n_coef": 1.4, "name": "Bambu PETG Basic", "nozzle_diameter": "0.4"}
+			],
+			"result": "success"
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+
+	if value := testutil.ToFloat64(exporter.pressureAdvanceMetric.WithLabelValues("GFA00", "Bambu PLA Basic", "0.4")); value != 0.02 {
+		t.Errorf("Expected K 0.02 for PLA, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.pressureAdvanceMetric.WithLabelValues("GFG00", "Bambu PETG Basic", "0.4")); value != 0.035 {
+		t.Errorf("Expected K 0.035 for PETG, got %v", value)
+	}
+}
+
+func TestAmsFilamentSetting(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{"print": {"command": "push_status", "ams": {"ams": [{"id": "0", "tray": [{"id": "1", "tray_type": "PLA", "tray_color": "FF0000FF"}]}]}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	reply := `{"print": {"command": "ams_filament_setting", "ams_id": 0, "tray_id": 1, "tray_type": "PETG", "tray_color": "00FF00FF", "result": "success"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+
+	if count := testutil.CollectAndCount(exporter.amsTypeMetric); count != 1 {
+		t.Errorf("Expected 1 tray type series, got %d", count)
+	}
+	if value := testutil.ToFloat64(exporter.amsTypeMetric.WithLabelValues("0", "1", "PETG")); value != 1 {
+		t.Errorf("Expected tray type PETG, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.amsColorMetric.WithLabelValues("0", "1", "00FF00FF")); value != 1 {
+		t.Errorf("Expected tray color 00FF00FF, got %v", value)
+	}
+
+	// The external spool is not an AMS tray.
+	reply = `{"print": {"command": "ams_filament_setting", "ams_id": 255, "tray_id": 254, "tray_type": "TPU", "tray_color": "000000FF", "result": "success"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+
+	if count := testutil.CollectAndCount(exporter.amsTypeMetric); count != 1 {
+		t.Errorf("Expected the external spool to be ignored, got %d tray type series", count)
+	}
+}
@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// printCommands lists the print commands exposed by the control API.
+var printCommands = []string{"pause", "resume", "stop"}
+
+// invalidRequestError is returned by control commands when the request
+// parameters are invalid.
+type invalidRequestError string
+
+func (e invalidRequestError) Error() string {
+	return string(e)
+}
+
+// registerControl registers the control endpoints that are enabled. Light
+// control can be enabled on its own with BAMBULABS_LIGHT_CONTROL, everything
+// else needs BAMBULABS_CONTROL_API.
+func (e *Exporter) registerControl(mux *http.ServeMux) {
+	if e.config.LightControl || e.config.ControlAPI {
+		mux.HandleFunc("/control/light", e.lightControl)
+	}
+	if !e.config.ControlAPI {
+		return
+	}
+	for _, command := range printCommands {
+		mux.HandleFunc("/control/print/"+command, e.controlHandler(command, func(r *http.Request) (string, error) {
+			return "", e.sendPrintCommand(e.client, command, "")
+		}))
+	}
+	mux.HandleFunc("/control/speed", e.controlHandler("speed", e.publishSpeedLevel))
+}
+
+// controlHandler wraps a control command. Only POST requests carrying the
+// configured token are accepted, and every attempt is logged and counted in
+// bambulabs_control_commands_total. publish validates the request, publishes
+// the command and returns its parameters for the audit log.
+func (e *Exporter) controlHandler(command string, publish func(r *http.Request) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !e.authorized(r) {
+			e.audit(r, command, "", "unauthorized")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if e.client == nil {
+			// Replaying a recording, there is no printer to send commands to.
+			e.audit(r, command, "", "unavailable")
+			http.Error(w, "not connected to the printer", http.StatusServiceUnavailable)
+			return
+		}
+
+		params, err := publish(r)
+		var invalid invalidRequestError
+		switch {
+		case errors.As(err, &invalid):
+			e.audit(r, command, params, "invalid")
+			http.Error(w, invalid.Error(), http.StatusBadRequest)
+		case err != nil:
+			e.audit(r, command, params, "error")
+			fmt.Printf("Error publishing %s: %s\n", command, err)
+			http.Error(w, "failed to publish command", http.StatusBadGateway)
+		default:
+			e.audit(r, command, params, "ok")
+			fmt.Fprint(w, "OK")
+		}
+	}
+}
+
+// authorized reports whether the request carries the configured token as a
+// bearer token. Without a configured token no request is authorized.
+func (e *Exporter) authorized(r *http.Request) bool {
+	if e.config.ControlToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(e.config.ControlToken)) == 1
+}
+
+// audit logs and counts a control command.
+func (e *Exporter) audit(r *http.Request, command, params, result string) {
+	fmt.Printf("Control command %s from %s: %s\n", strings.TrimSpace(command+" "+params), r.RemoteAddr, result)
+	e.controlCommandsMetric.WithLabelValues(command, result).Inc()
+}
+
+// publishSpeedLevel changes the speed level to the level parameter, either a
+// name from speedLevels or its number.
+//
+//	POST /control/speed?level=sport
+func (e *Exporter) publishSpeedLevel(r *http.Request) (string, error) {
+	name := r.FormValue("level")
+	params := "level=" + name
+
+	for level, levelName := range speedLevels {
+		if name == levelName || name == strconv.Itoa(level) {
+			return params, e.setSpeedLevel(e.client, level)
+		}
+	}
+	return params, invalidRequestError(fmt.Sprintf("unknown speed level %q", name))
+}
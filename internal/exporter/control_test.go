@@ -0,0 +1,268 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testBroker is a minimal MQTT broker that acknowledges connections and
+// subscriptions and records what is published to it.
+type testBroker struct {
+	listener  net.Listener
+	published chan *packets.PublishPacket
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	broker := &testBroker{listener: listener, published: make(chan *packets.PublishPacket, 16)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	return broker
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		var reply packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			reply = packets.NewControlPacket(packets.Connack)
+		case *packets.SubscribePacket:
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = p.Qoss
+			reply = suback
+		case *packets.PublishPacket:
+			b.published <- p
+			if p.Qos > 0 {
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				reply = puback
+			}
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+		if reply != nil && reply.Write(conn) != nil {
+			return
+		}
+	}
+}
+
+// connect returns a client connected to the broker.
+func (b *testBroker) connect(t *testing.T) mqtt.Client {
+	t.Helper()
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker("tcp://" + b.listener.Addr().String())
+	opts.SetClientID("control-test")
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		t.Fatalf("Failed to connect: %v", token.Error())
+	}
+	t.Cleanup(func() { client.Disconnect(0) })
+	return client
+}
+
+// next returns the next message published to the broker.
+func (b *testBroker) next(t *testing.T) *packets.PublishPacket {
+	t.Helper()
+
+	select {
+	case packet := <-b.published:
+		return packet
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a published message")
+		return nil
+	}
+}
+
+func TestControlAPI(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_CONTROL_API":   "true",
+		"BAMBULABS_CONTROL_TOKEN": "secret",
+	})
+	broker := newTestBroker(t)
+	exporter.client = broker.connect(t)
+
+	mux := http.NewServeMux()
+	exporter.registerControl(mux)
+
+	tests := []struct {
+		name    string
+		target  string
+		command string
+		param   string
+	}{
+		{"pause", "/control/print/pause", "pause", ""},
+		{"resume", "/control/print/resume", "resume", ""},
+		{"stop", "/control/print/stop", "stop", ""},
+		{"speed by name", "/control/speed?level=sport", "print_speed", "3"},
+		{"speed by number", "/control/speed?level=1", "print_speed", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body)
+			}
+
+			packet := broker.next(t)
+			if packet.TopicName != "device/test123/request" {
+				t.Errorf("Expected topic 'device/test123/request', got '%s'", packet.TopicName)
+			}
+			var request printRequest
+			if err := json.Unmarshal(packet.Payload, &request); err != nil {
+				t.Fatalf("Failed to unmarshal request: %v", err)
+			}
+			if request.Print.Command != tt.command || request.Print.Param != tt.param {
+				t.Errorf("Expected %s %q, got %+v", tt.command, tt.param, request.Print)
+			}
+		})
+	}
+
+	if value := testutil.ToFloat64(exporter.controlCommandsMetric.WithLabelValues("pause", "ok")); value != 1 {
+		t.Errorf("Expected 1 audited pause, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.controlCommandsMetric.WithLabelValues("speed", "ok")); value != 2 {
+		t.Errorf("Expected 2 audited speed changes, got %v", value)
+	}
+}
+
+func TestControlAPIRejects(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_CONTROL_API":   "true",
+		"BAMBULABS_CONTROL_TOKEN": "secret",
+	})
+	client := &mockClient{}
+	exporter.client = client
+
+	mux := http.NewServeMux()
+	exporter.registerControl(mux)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		token          string
+		expectedStatus int
+	}{
+		{"missing token", http.MethodPost, "/control/print/stop", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/control/print/stop", "guess", http.StatusUnauthorized},
+		{"wrong method", http.MethodGet, "/control/print/stop", "secret", http.StatusMethodNotAllowed},
+		{"unknown speed", http.MethodPost, "/control/speed?level=warp", "secret", http.StatusBadRequest},
+		{"unknown light", http.MethodPost, "/control/light?node=laser&mode=on", "secret", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+
+	if len(client.published) != 0 {
+		t.Errorf("Expected no published commands, got %d", len(client.published))
+	}
+	if value := testutil.ToFloat64(exporter.controlCommandsMetric.WithLabelValues("stop", "unauthorized")); value != 2 {
+		t.Errorf("Expected 2 unauthorized stops, got %v", value)
+	}
+}
+
+func TestControlWithoutToken(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	// NewExporter refuses to enable control without a token, so bypass it to
+	// check the handlers fail closed.
+	exporter.config.LightControl = true
+	client := &mockClient{}
+	exporter.client = client
+
+	mux := http.NewServeMux()
+	exporter.registerControl(mux)
+
+	for _, token := range []string{"", "Bearer "} {
+		req := httptest.NewRequest(http.MethodPost, "/control/light?mode=on", nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with authorization %q, got %d", token, rr.Code)
+		}
+	}
+
+	if len(client.published) != 0 {
+		t.Errorf("Expected no published commands, got %d", len(client.published))
+	}
+}
+
+func TestControlWithoutClient(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_CONTROL_API":   "true",
+		"BAMBULABS_CONTROL_TOKEN": "secret",
+	})
+
+	mux := http.NewServeMux()
+	exporter.registerControl(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/control/light?mode=on", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a printer connection, got %d", rr.Code)
+	}
+}
+
+func TestControlAPIDisabled(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	mux := http.NewServeMux()
+	exporter.registerControl(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/control/print/stop", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+}
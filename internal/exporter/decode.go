@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// decodeMessage decodes a message into T. Fields that have the wrong type or
+// an invalid numeric value are counted in bambulabs_decode_errors_total and
+// left at their zero value, so one bad field does not drop the whole message.
+// With strict decoding enabled such messages are dropped instead. ok is false
+// when the message should be ignored.
+func decodeMessage[T any](e *Exporter, payload []byte) (data T, ok bool) {
+	var fields []string
+
+	err := json.Unmarshal(payload, &data)
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		// Unmarshal only reports the first mistyped field, so look for the
+		// others in the generic form of the message.
+		var message any
+		if json.Unmarshal(payload, &message) == nil {
+			fields = typeMismatches(reflect.TypeFor[T](), message, "")
+		}
+		if len(fields) == 0 {
+			fields = append(fields, typeErr.Field)
+		}
+	case err != nil:
+		fmt.Printf("Error unmarshalling JSON: %s\n", err)
+		return data, false
+	}
+
+	fields = append(fields, invalidNumbers(reflect.ValueOf(data), "")...)
+	for _, field := range fields {
+		e.decodeErrorsMetric.WithLabelValues(field).Inc()
+	}
+
+	if len(fields) > 0 && e.config.StrictDecoding {
+		fmt.Printf("Dropping message with invalid fields: %s\n", strings.Join(fields, ", "))
+		return data, false
+	}
+	return data, true
+}
+
+var numberType = reflect.TypeFor[Number]()
+
+// invalidNumbers returns the JSON paths of the Number fields in v that could
+// not be decoded, e.g. print.ams.ams.tray.remain. Slice indices are left out
+// of the path to keep the number of distinct paths small.
+func invalidNumbers(v reflect.Value, path string) []string {
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == numberType {
+			if v.Interface().(Number).invalid {
+				return []string{path}
+			}
+			return nil
+		}
+
+		var fields []string
+		for i := range v.NumField() {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			if path != "" {
+				name = path + "." + name
+			}
+			fields = append(fields, invalidNumbers(v.Field(i), name)...)
+		}
+		return fields
+	case reflect.Slice:
+		var fields []string
+		for i := range v.Len() {
+			fields = append(fields, invalidNumbers(v.Index(i), path)...)
+		}
+		// Report a path once even if several items are invalid.
+		slices.Sort(fields)
+		return slices.Compact(fields)
+	}
+	return nil
+}
+
+// typeMismatches returns the JSON paths of the values in message that cannot
+// be decoded into a field of type t, in the same form as invalidNumbers.
+// Number fields accept any value and are checked by invalidNumbers instead.
+func typeMismatches(t reflect.Type, message any, path string) []string {
+	if message == nil {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeMismatches(t.Elem(), message, path)
+	case reflect.Interface:
+		return nil
+	case reflect.Struct:
+		if t == numberType {
+			return nil
+		}
+		object, ok := message.(map[string]any)
+		if !ok {
+			return []string{path}
+		}
+
+		var fields []string
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			value, ok := object[name]
+			if !ok {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			fields = append(fields, typeMismatches(field.Type, value, name)...)
+		}
+		return fields
+	case reflect.Slice:
+		items, ok := message.([]any)
+		if !ok {
+			return []string{path}
+		}
+
+		var fields []string
+		for _, item := range items {
+			fields = append(fields, typeMismatches(t.Elem(), item, path)...)
+		}
+		// Report a path once even if several items are mistyped.
+		slices.Sort(fields)
+		return slices.Compact(fields)
+	case reflect.Map:
+		object, ok := message.(map[string]any)
+		if !ok {
+			return []string{path}
+		}
+
+		var fields []string
+		for _, value := range object {
+			fields = append(fields, typeMismatches(t.Elem(), value, path)...)
+		}
+		slices.Sort(fields)
+		return slices.Compact(fields)
+	case reflect.String:
+		if _, ok := message.(string); !ok {
+			return []string{path}
+		}
+	case reflect.Bool:
+		if _, ok := message.(bool); !ok {
+			return []string{path}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, ok := message.(float64); !ok {
+			return []string{path}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Event types sent to notifiers.
+const (
+	EventJobStarted  = "job_started"
+	EventJobFinished = "job_finished"
+	EventJobFailed   = "job_failed"
+	EventJobPaused   = "job_paused"
+	EventHMSError    = "hms_error"
+	EventFilamentLow = "filament_low"
+)
+
+// Event is a print event derived from the changes between consecutive
+// reports. It is the data passed to webhook templates.
+type Event struct {
+	Type             string    `json:"type"`
+	Time             time.Time `json:"time"`
+	Message          string    `json:"message"`
+	Serial           string    `json:"serial"`
+	Model            string    `json:"model"`
+	State            string    `json:"state"`
+	Job              string    `json:"job,omitempty"`
+	File             string    `json:"file,omitempty"`
+	Progress         float64   `json:"progress"`
+	RemainingSeconds float64   `json:"remaining_seconds"`
+	Layer            int       `json:"layer"`
+	TotalLayers      int       `json:"total_layers"`
+	HMSCode          string    `json:"hms_code,omitempty"`
+	AMS              string    `json:"ams,omitempty"`
+	Tray             string    `json:"tray,omitempty"`
+	TrayType         string    `json:"tray_type,omitempty"`
+	Remain           float64   `json:"remain,omitempty"`
+}
+
+// hmsEntry is an active Health Management System message.
+type hmsEntry struct {
+	Attr Number `json:"attr"`
+	Code Number `json:"code"`
+}
+
+// String returns the code in the format used by the Bambu Lab wiki, e.g.
+// 0300_0100_0001_0007.
+func (h hmsEntry) String() string {
+	attr, code := uint32(h.Attr.Int()), uint32(h.Code.Int())
+	return fmt.Sprintf("%04X_%04X_%04X_%04X", attr>>16, attr&0xFFFF, code>>16, code&0xFFFF)
+}
+
+// eventState keeps the last reported job details, as partial reports only
+// include the fields that changed, and the HMS codes and low trays already
+// notified about.
+type eventState struct {
+	job         string
+	file        string
+	progress    float64
+	remaining   float64
+	layer       int
+	totalLayers int
+	hms         map[string]bool
+	lowTrays    map[string]bool
+}
+
+// updateEvents emits the events for the changes in a push_status report. It
+// must run before e.gcodeState is updated to the new state.
+func (e *Exporter) updateEvents(data BambuLabsX1C) {
+	e.updateEventState(data)
+
+	switch state, previous := data.Print.GcodeState, e.gcodeState; {
+	case state == previous || state == "" || previous == "":
+	case state == "RUNNING" && previous != "PAUSE":
+		e.emit(e.jobEvent(EventJobStarted, state, e.jobName()+" started"))
+	case state == "FINISH":
+		e.emit(e.jobEvent(EventJobFinished, state, e.jobName()+" finished"))
+	case state == "FAILED":
+		e.emit(e.jobEvent(EventJobFailed, state, e.jobName()+" failed"))
+	case state == "PAUSE":
+		e.emit(e.jobEvent(EventJobPaused, state, e.jobName()+" paused"))
+	}
+
+	if data.Print.Hms != nil {
+		active := map[string]bool{}
+		for _, hms := range data.Print.Hms {
+			code := hms.String()
+			active[code] = true
+			if !e.events.hms[code] {
+				event := e.jobEvent(EventHMSError, e.gcodeState, "HMS error "+code+" on "+e.jobName())
+				event.HMSCode = code
+				e.emit(event)
+			}
+		}
+		e.events.hms = active
+	}
+
+	for _, ams := range data.Print.Ams.Ams {
+		for _, tray := range ams.Tray {
+			if tray.TrayType == "" || !tray.Remain.Present() || tray.Remain.Float64() < 0 {
+				continue
+			}
+
+			key := ams.ID + "/" + tray.ID
+			low := tray.Remain.Float64() < e.config.FilamentLowPercent
+			if low && !e.events.lowTrays[key] {
+				event := e.jobEvent(EventFilamentLow, e.gcodeState,
+					fmt.Sprintf("%s in AMS %s tray %s is running low (%.0f%%)", tray.TrayType, ams.ID, tray.ID, tray.Remain.Float64()))
+				event.AMS, event.Tray, event.TrayType, event.Remain = ams.ID, tray.ID, tray.TrayType, tray.Remain.Float64()
+				e.emit(event)
+			}
+			if e.events.lowTrays == nil {
+				e.events.lowTrays = map[string]bool{}
+			}
+			e.events.lowTrays[key] = low
+		}
+	}
+}
+
+// updateEventState records the job details included in a report.
+func (e *Exporter) updateEventState(data BambuLabsX1C) {
+	if data.Print.SubtaskName != "" {
+		e.events.job = data.Print.SubtaskName
+	}
+	if data.Print.GcodeFile != "" {
+		e.events.file = data.Print.GcodeFile
+	}
+	if data.Print.McPercent.Present() {
+		e.events.progress = data.Print.McPercent.Float64()
+	}
+	if data.Print.McRemainingTime.Present() {
+		e.events.remaining = data.Print.McRemainingTime.Float64() * 60
+	}
+	if data.Print.LayerNum.Present() {
+		e.events.layer = data.Print.LayerNum.Int()
+	}
+	if data.Print.TotalLayerNum.Present() {
+		e.events.totalLayers = data.Print.TotalLayerNum.Int()
+	}
+}
+
+// jobName returns the name of the current job for event messages.
+func (e *Exporter) jobName() string {
+	if e.events.job == "" {
+		return "Print"
+	}
+	return e.events.job
+}
+
+// jobEvent returns an event describing the current job.
+func (e *Exporter) jobEvent(eventType, state, message string) Event {
+	return Event{
+		Type:             eventType,
+		Time:             e.now(),
+		Message:          message,
+		Serial:           e.serial(),
+		Model:            string(e.model),
+		State:            state,
+		Job:              e.events.job,
+		File:             e.events.file,
+		Progress:         e.events.progress,
+		RemainingSeconds: e.events.remaining,
+		Layer:            e.events.layer,
+		TotalLayers:      e.events.totalLayers,
+	}
+}
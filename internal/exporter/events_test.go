@@ -0,0 +1,133 @@
+package exporter
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// recordingNotifier records the events it receives.
+type recordingNotifier struct {
+	eventFilter
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recordingNotifier) name() string {
+	return "recording"
+}
+
+func (r *recordingNotifier) notify(ctx context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// types returns the types of the recorded events in order of delivery.
+func (r *recordingNotifier) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []string
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestHMSCode(t *testing.T) {
+	hms := hmsEntry{Attr: NewNumber(0x03000100), Code: NewNumber(0x00010007)}
+	if code := hms.String(); code != "0300_0100_0001_0007" {
+		t.Errorf("Expected 0300_0100_0001_0007, got %s", code)
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	recorder := &recordingNotifier{}
+	exporter.notifiers = []notifier{recorder}
+
+	reports := []string{
+		`{"print": {"command": "push_status", "gcode_state": "IDLE"}}`,
+		`{"print": {"command": "push_status", "gcode_state": "PREPARE", "subtask_name": "benchy", "gcode_file": "benchy.gcode"}}`,
+		`{"print": {"command": "push_status", "gcode_state": "RUNNING", "mc_percent": 10}}`,
+		`{"print": {"command": "push_status", "mc_percent": 40, "mc_remaining_time": 30}}`,
+		`{"print": {"command": "push_status", "gcode_state": "PAUSE"}}`,
+		`{"print": {"command": "push_status", "gcode_state": "RUNNING"}}`,
+		`{"print": {"command": "push_status", "gcode_state": "FINISH", "mc_percent": 100}}`,
+		`{"print": {"command": "push_status", "gcode_state": "RUNNING", "subtask_name": "cube"}}`,
+		`{"print": {"command": "push_status", "gcode_state": "FAILED"}}`,
+	}
+	for _, report := range reports {
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+	}
+	exporter.deliveries.Wait()
+
+	expected := []string{EventJobStarted, EventJobPaused, EventJobFinished, EventJobStarted, EventJobFailed}
+	if types := recorder.types(); !slices.Equal(types, expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+
+	paused := recorder.events[1]
+	if paused.Job != "benchy" || paused.File != "benchy.gcode" || paused.Progress != 40 || paused.RemainingSeconds != 1800 {
+		t.Errorf("Expected paused event to carry the job details, got %+v", paused)
+	}
+	if paused.Message != "benchy paused" {
+		t.Errorf("Expected message 'benchy paused', got '%s'", paused.Message)
+	}
+	if failed := recorder.events[4]; failed.Job != "cube" {
+		t.Errorf("Expected failed event for cube, got %s", failed.Job)
+	}
+	if value := testutil.ToFloat64(exporter.eventsMetric.WithLabelValues(EventJobStarted)); value != 2 {
+		t.Errorf("Expected 2 job_started events, got %v", value)
+	}
+}
+
+func TestHMSAndFilamentEvents(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_FILAMENT_LOW_PERCENT": "15"})
+	recorder := &recordingNotifier{}
+	exporter.notifiers = []notifier{recorder}
+
+	reports := []string{
+		`{"print": {"command": "push_status", "hms": [{"attr": 50331904, "code": 65543}], "ams": {"ams": [{"id": "0", "tray": [{"id": "0", "tray_type": "PLA", "remain": 20}, {"id": "1", "tray_type": "", "remain": 0}]}]}}}`,
+		// Partial reports without hms keep the active codes.
+		`{"print": {"command": "push_status", "ams": {"ams": [{"id": "0", "tray": [{"id": "0", "tray_type": "PLA", "remain": 12}]}]}}}`,
+		`{"print": {"command": "push_status", "hms": [{"attr": 50331904, "code": 65543}], "ams": {"ams": [{"id": "0", "tray": [{"id": "0", "tray_type": "PLA", "remain": 10}]}]}}}`,
+		`{"print": {"command": "push_status", "hms": []}}`,
+		`{"print": {"command": "push_status", "hms": [{"attr": 50331904, "code": 65543}]}}`,
+	}
+	for _, report := range reports {
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+	}
+	exporter.deliveries.Wait()
+
+	expected := []string{EventHMSError, EventFilamentLow, EventHMSError}
+	if types := recorder.types(); !slices.Equal(types, expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+	if code := recorder.events[0].HMSCode; code != "0300_0100_0001_0007" {
+		t.Errorf("Expected HMS code 0300_0100_0001_0007, got %s", code)
+	}
+	if low := recorder.events[1]; low.AMS != "0" || low.Tray != "0" || low.Remain != 12 {
+		t.Errorf("Expected low filament in AMS 0 tray 0 at 12%%, got %+v", low)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	recorder := &recordingNotifier{eventFilter: eventFilter{EventJobFinished}}
+	exporter.notifiers = []notifier{recorder}
+
+	for _, state := range []string{"IDLE", "RUNNING", "FINISH"} {
+		report := `{"print": {"command": "push_status", "gcode_state": "` + state + `"}}`
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+	}
+	exporter.deliveries.Wait()
+
+	if types := recorder.types(); !slices.Equal(types, []string{EventJobFinished}) {
+		t.Errorf("Expected only job_finished, got %v", types)
+	}
+}
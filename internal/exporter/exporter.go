@@ -3,12 +3,11 @@ package exporter
 import (
 	"cmp"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -16,43 +15,163 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
 
+const namespace = "bambulabs"
+
 type Config struct {
-	Debug    bool
-	Username string
-	Password string
-	IP       string
-	Topic    string
+	Debug         bool
+	Username      string
+	Password      string
+	IP            string
+	Port          int `default:"8883"`
+	Topic         string
+	Model         string
+	LegacyMetrics bool            `split_words:"true"`
+	LightControl  bool            `split_words:"true"`
+	WifiWindows   []time.Duration `split_words:"true" default:"5m,1h"`
+
+	StrictDecoding bool   `split_words:"true"`
+	ControlAPI     bool   `split_words:"true"`
+	ControlToken   string `split_words:"true"`
+
+	WebhookURLs        []string      `envconfig:"WEBHOOK_URLS"`
+	WebhookEvents      []string      `split_words:"true"`
+	WebhookTemplate    string        `split_words:"true"`
+	WebhookContentType string        `split_words:"true" default:"application/json"`
+	NotifyRetries      int           `split_words:"true" default:"3"`
+	NotifyBackoff      time.Duration `split_words:"true" default:"1s"`
+	FilamentLowPercent float64       `split_words:"true" default:"10"`
+
+	ChatEvents        []string `split_words:"true" default:"job_finished,job_failed,hms_error"`
+	DiscordWebhookURL string   `split_words:"true"`
+	SlackWebhookURL   string   `split_words:"true"`
+	NtfyURL           string   `split_words:"true"`
+	NtfyToken         string   `split_words:"true"`
+	TelegramBotToken  string   `split_words:"true"`
+	TelegramChatID    string   `split_words:"true"`
+	TelegramAPIURL    string   `envconfig:"TELEGRAM_API_URL" default:"https://api.telegram.org"`
+
+	HomeassistantBroker          string `split_words:"true"`
+	HomeassistantUsername        string `split_words:"true"`
+	HomeassistantPassword        string `split_words:"true"`
+	HomeassistantDiscoveryPrefix string `split_words:"true" default:"homeassistant"`
+
+	ProxyAddress string `split_words:"true"`
+	ProxyTLSCert string `envconfig:"PROXY_TLS_CERT"`
+	ProxyTLSKey  string `envconfig:"PROXY_TLS_KEY"`
+
+	OtlpEndpoint string            `split_words:"true"`
+	OtlpProtocol string            `split_words:"true" default:"grpc"`
+	OtlpInterval time.Duration     `split_words:"true" default:"15s"`
+	OtlpHeaders  map[string]string `split_words:"true"`
+
+	PushURL      string            `envconfig:"PUSH_URL"`
+	PushFormat   string            `split_words:"true" default:"remote_write"`
+	PushInterval time.Duration     `split_words:"true" default:"30s"`
+	PushBuffer   int               `split_words:"true" default:"120"`
+	PushHeaders  map[string]string `split_words:"true"`
+
+	RecordFile string `split_words:"true"`
 }
 
 type Exporter struct {
-	config Config
-	client mqtt.Client
+	config   Config
+	client   mqtt.Client
+	registry *prometheus.Registry
+
+	sequenceID   atomic.Uint64
+	model        PrinterModel
+	now          func() time.Time
+	speed        speedState
+	gcodeState   string
+	uploadStatus string
+	layers       layerState
+	wifiSamples  []wifiSample
+	events       eventState
+	notifiers    []notifier
+	queues       map[notifier]chan Event
+	deliveries   sync.WaitGroup
+	// notifyMu guards notifyStopped, after which no events are queued.
+	notifyMu      sync.Mutex
+	notifyStopped bool
+
+	homeAssistant *homeAssistant
+	proxy         atomic.Pointer[proxy]
+	meterProvider *sdkmetric.MeterProvider
+	recorder      *recorder
 
 	// Metrics
-	amsHumidityMetric      *prometheus.GaugeVec
-	amsTempMetric          *prometheus.GaugeVec
-	amsColorMetric         *prometheus.GaugeVec
-	amsTypeMetric          *prometheus.GaugeVec
-	layerNumberMetric      prometheus.Gauge
-	printErrorMetric       prometheus.Gauge
-	wifiSignalMetric       prometheus.Gauge
-	bigFan1SpeedMetric     prometheus.Gauge
-	bigFan2SpeedMetric     prometheus.Gauge
-	chamberTemperMetric    prometheus.Gauge
-	coolingFanSpeedMetric  prometheus.Gauge
-	failReasonMetric       prometheus.Gauge
-	fanGearMetric          prometheus.Gauge
-	mcPercentMetric        prometheus.Gauge
-	mcPrintErrorCodeMetric prometheus.Gauge
-	mcPrintStageMetric     prometheus.Gauge
-	mcPrintSubStageMetric  prometheus.Gauge
-	mcRemainingTimeMetric  prometheus.Gauge
+	amsHumidityMetric        *prometheus.GaugeVec
+	amsTempMetric            *prometheus.GaugeVec
+	amsColorMetric           *prometheus.GaugeVec
+	amsTypeMetric            *prometheus.GaugeVec
+	layerNumberMetric        prometheus.Gauge
+	printErrorMetric         prometheus.Gauge
+	wifiSignalMetric         prometheus.Gauge
+	chamberTemperMetric      prometheus.Gauge
+	fanSpeedMetric           *prometheus.GaugeVec
+	failReasonMetric         prometheus.Gauge
+	fanGearMetric            prometheus.Gauge
+	mcPercentMetric          prometheus.Gauge
+	mcPrintErrorCodeMetric   prometheus.Gauge
+	mcPrintStageMetric       prometheus.Gauge
+	mcPrintSubStageMetric    prometheus.Gauge
+	mcRemainingTimeMetric    prometheus.Gauge
 	nozzleTargetTemperMetric prometheus.Gauge
-	nozzleTemperMetric     prometheus.Gauge
+	nozzleTemperMetric       prometheus.Gauge
 	bedTargetTemperMetric    prometheus.Gauge
-	bedTemperMetric    prometheus.Gauge
+	bedTemperMetric          prometheus.Gauge
+	moduleInfoMetric         *prometheus.GaugeVec
+	firmwareUpdateMetric     *prometheus.GaugeVec
+	upgradeStateMetric       prometheus.Gauge
+	upgradeProgressMetric    prometheus.Gauge
+	upgradeErrorCodeMetric   prometheus.Gauge
+	printerInfoMetric        *prometheus.GaugeVec
+
+	speedLevelMetric           *prometheus.GaugeVec
+	speedMagnitudeMetric       prometheus.Gauge
+	speedLevelSecondsMetric    *prometheus.CounterVec
+	jobSpeedLevelSecondsMetric *prometheus.GaugeVec
+	lightOnMetric              *prometheus.GaugeVec
+	cameraRecordingMetric      prometheus.Gauge
+	timelapseEnabledMetric     prometheus.Gauge
+	cameraResolutionMetric     *prometheus.GaugeVec
+	xcamDetectorMetric         *prometheus.GaugeVec
+	xcamSensitivityMetric      prometheus.Gauge
+	xcamStatusMetric           prometheus.Gauge
+	xcamHaltsMetric            *prometheus.CounterVec
+	uploadProgressMetric       prometheus.Gauge
+	uploadSizeMetric           prometheus.Gauge
+	uploadFinishedMetric       prometheus.Gauge
+	uploadSpeedMetric          prometheus.Gauge
+	uploadRemainingMetric      prometheus.Gauge
+	uploadStatusMetric         *prometheus.GaugeVec
+	uploadFailuresMetric       *prometheus.CounterVec
+	flagMetric                 *prometheus.GaugeVec
+	sdcardPresentMetric        prometheus.Gauge
+	sdcardStateMetric          *prometheus.GaugeVec
+	totalLayersMetric          prometheus.Gauge
+	layerProgressMetric        prometheus.Gauge
+	printETAMetric             prometheus.Gauge
+	estimatedETAMetric         prometheus.Gauge
+	jobInfoMetric              *prometheus.GaugeVec
+	wifiSignalErrorsMetric     prometheus.Counter
+	wifiQualityMetric          prometheus.Gauge
+	wifiSignalMinMetric        *prometheus.GaugeVec
+	wifiSignalAvgMetric        *prometheus.GaugeVec
+	decodeErrorsMetric         *prometheus.CounterVec
+	unknownCommandsMetric      *prometheus.CounterVec
+	commandResultsMetric       *prometheus.CounterVec
+	pressureAdvanceMetric      *prometheus.GaugeVec
+	controlCommandsMetric      *prometheus.CounterVec
+	eventsMetric               *prometheus.CounterVec
+	notificationsMetric        *prometheus.CounterVec
+	proxyClientsMetric         prometheus.Gauge
+	proxyMessagesMetric        *prometheus.CounterVec
+	pushesMetric               *prometheus.CounterVec
+	pushBufferedMetric         prometheus.Gauge
 }
 
 func NewExporter() *Exporter {
@@ -61,112 +180,389 @@ func NewExporter() *Exporter {
 	if err != nil {
 		panic(err)
 	}
+	if cfg.ControlAPI && cfg.ControlToken == "" {
+		panic("BAMBULABS_CONTROL_TOKEN is required when BAMBULABS_CONTROL_API is enabled")
+	}
+	if cfg.LightControl && cfg.ControlToken == "" {
+		panic("BAMBULABS_CONTROL_TOKEN is required when BAMBULABS_LIGHT_CONTROL is enabled")
+	}
+	for _, window := range cfg.WifiWindows {
+		if window <= 0 {
+			panic(fmt.Sprintf("BAMBULABS_WIFI_WINDOWS must be positive, got %s", window))
+		}
+	}
 
 	exporter := &Exporter{
-		config: cfg,
+		config:   cfg,
+		registry: prometheus.NewRegistry(),
+		now:      time.Now,
+	}
+
+	exporter.notifiers, err = newWebhooks(cfg)
+	if err != nil {
+		panic(err)
+	}
+	exporter.notifiers = append(exporter.notifiers, newChatNotifiers(cfg)...)
+	exporter.recorder, err = newRecorder(cfg.RecordFile)
+	if err != nil {
+		panic(err)
 	}
 
 	exporter.initMetrics()
+	exporter.setModel(exporter.detectModel())
 	return exporter
 }
 
 func (e *Exporter) initMetrics() {
-	e.amsHumidityMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "ams_humidity",
-		Help: "humidity of the ams",
+	factory := promauto.With(e.registry)
+
+	e.amsHumidityMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ams_humidity_index",
+		Help:      "Humidity index of the AMS as reported by the printer (1-5)",
 	}, []string{"ams_number"})
-	e.amsTempMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "ams_temp",
-		Help: "temperature of the ams",
+	e.amsTempMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ams_temperature_celsius",
+		Help:      "Temperature of the AMS in degrees Celsius",
 	}, []string{"ams_number"})
-	e.amsColorMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "ams_tray_color",
-		Help: "color of material in ams tray",
+	e.amsColorMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ams_tray_color_info",
+		Help:      "Color of the material in an AMS tray",
 	}, []string{"ams_number", "tray_number", "tray_color"})
-	e.amsTypeMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "ams_tray_type",
-		Help: "type of material in ams tray",
+	e.amsTypeMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ams_tray_type_info",
+		Help:      "Type of the material in an AMS tray",
 	}, []string{"ams_number", "tray_number", "tray_type"})
-	e.layerNumberMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "layer_number",
-		Help: "layer number of the print head in gcode",
+	e.layerNumberMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "layer_number",
+		Help:      "Layer number of the print head in gcode",
+	})
+	e.printErrorMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "print_error_code",
+		Help:      "Print error reported by the control board",
+	})
+	e.wifiSignalMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wifi_signal_dbm",
+		Help:      "Wifi signal strength in dBm",
+	})
+	e.chamberTemperMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chamber_temperature_celsius",
+		Help:      "Chamber temperature of the printer in degrees Celsius",
+	})
+	e.fanSpeedMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fan_speed_percent",
+		Help:      "Fan speed in percent (part_cooling, aux, chamber, heatbreak)",
+	}, []string{"fan"})
+	e.failReasonMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "print_fail_reason_code",
+		Help:      "Print failure reason code",
+	})
+	e.fanGearMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fan_gear",
+		Help:      "Packed fan gear value",
+	})
+	e.mcPercentMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "print_progress_percent",
+		Help:      "Progress of the current print in percent",
+	})
+	e.mcPrintErrorCodeMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mc_print_error_code",
+		Help:      "Print progress error code",
+	})
+	e.mcPrintStageMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mc_print_stage",
+		Help:      "Print progress stage",
+	})
+	e.mcPrintSubStageMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mc_print_sub_stage",
+		Help:      "Print progress sub stage",
+	})
+	e.mcRemainingTimeMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "print_remaining_seconds",
+		Help:      "Estimated remaining time of the current print in seconds",
+	})
+	e.nozzleTargetTemperMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "nozzle_target_temperature_celsius",
+		Help:      "Nozzle target temperature in degrees Celsius",
+	})
+	e.nozzleTemperMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "nozzle_temperature_celsius",
+		Help:      "Nozzle temperature in degrees Celsius",
+	})
+	e.bedTargetTemperMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bed_target_temperature_celsius",
+		Help:      "Bed target temperature in degrees Celsius",
+	})
+	e.bedTemperMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bed_temperature_celsius",
+		Help:      "Bed temperature in degrees Celsius",
+	})
+	e.moduleInfoMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "module_info",
+		Help:      "Firmware and hardware versions of the printer modules as reported by get_version",
+	}, []string{"module", "sw_ver", "hw_ver", "sn"})
+	e.firmwareUpdateMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "firmware_update_info",
+		Help:      "Firmware version available for a module that has not been installed yet",
+	}, []string{"module", "version"})
+	e.upgradeStateMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upgrade_new_version_state",
+		Help:      "New firmware version state (1 = update available, 2 = up to date)",
+	})
+	e.upgradeProgressMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upgrade_progress_percent",
+		Help:      "Progress of a running firmware upgrade in percent",
+	})
+	e.upgradeErrorCodeMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upgrade_error_code",
+		Help:      "Error code of the last firmware upgrade",
+	})
+	e.printerInfoMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "printer_info",
+		Help:      "Detected printer model and serial number",
+	}, []string{"model", "serial"})
+	e.speedLevelMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "speed_level",
+		Help:      "Active print speed level (1 for the active level, 0 otherwise)",
+	}, []string{"level"})
+	e.speedMagnitudeMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "speed_magnitude_percent",
+		Help:      "Print speed magnitude in percent of the standard speed",
+	})
+	e.speedLevelSecondsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "speed_level_seconds_total",
+		Help:      "Time spent printing at each speed level in seconds",
+	}, []string{"level"})
+	e.jobSpeedLevelSecondsMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_speed_level_seconds",
+		Help:      "Time spent printing at each speed level during the current job in seconds",
+	}, []string{"level"})
+	e.lightOnMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "light_on",
+		Help:      "Whether a light is on (1) or off (0)",
+	}, []string{"node"})
+	e.cameraRecordingMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "camera_recording",
+		Help:      "Whether the camera is recording (1) or not (0)",
 	})
-	e.printErrorMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "print_error",
-		Help: "Print error int",
+	e.timelapseEnabledMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "timelapse_enabled",
+		Help:      "Whether timelapse recording is enabled (1) or not (0)",
 	})
-	e.wifiSignalMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "wifi_signal",
-		Help: "Wifi signal in dBm",
+	e.cameraResolutionMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "camera_resolution_info",
+		Help:      "Resolution of the camera",
+	}, []string{"resolution"})
+	e.xcamDetectorMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "xcam_detector_enabled",
+		Help:      "Whether an X-Cam AI detector is enabled (1) or not (0)",
+	}, []string{"detector"})
+	e.xcamSensitivityMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "xcam_halt_sensitivity",
+		Help:      "Sensitivity at which the X-Cam halts a print (1 = low, 2 = medium, 3 = high)",
 	})
-	e.bigFan1SpeedMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "big_fan1_speed",
-		Help: "Big Fan 1 Speed",
+	e.xcamStatusMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "xcam_status",
+		Help:      "X-Cam status code",
 	})
-	e.bigFan2SpeedMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "big_fan2_speed",
-		Help: "Big Fan 2 Speed",
+	e.xcamHaltsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "xcam_halts_total",
+		Help:      "Number of prints halted by the X-Cam, by print error code",
+	}, []string{"error"})
+	e.uploadProgressMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upload_progress_percent",
+		Help:      "Progress of the file upload to the printer in percent",
 	})
-	e.chamberTemperMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "chamber_temper",
-		Help: "Chamber Temperature of Printer",
+	e.uploadSizeMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of the file being uploaded to the printer in bytes",
 	})
-	e.coolingFanSpeedMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "cooling_fan_speed",
-		Help: "Cooling Fan Speed",
+	e.uploadFinishedMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upload_finished_bytes",
+		Help:      "Bytes of the file already uploaded to the printer",
 	})
-	e.failReasonMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "fail_reason",
-		Help: "Print Failure Reason",
+	e.uploadSpeedMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upload_speed_bytes_per_second",
+		Help:      "Speed of the file upload to the printer in bytes per second",
 	})
-	e.fanGearMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "fan_gear",
-		Help: "Fan Gear",
+	e.uploadRemainingMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upload_remaining_seconds",
+		Help:      "Estimated remaining time of the file upload in seconds",
 	})
-	e.mcPercentMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "mc_percent",
-		Help: "Percentage of Progress of print",
+	e.uploadStatusMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upload_status_info",
+		Help:      "Status of the file upload to the printer",
+	}, []string{"status"})
+	e.uploadFailuresMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_failures_total",
+		Help:      "Number of failed file uploads to the printer",
+	}, []string{"trouble_id"})
+	e.flagMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "flag",
+		Help:      "State of a status flag decoded from home_flag or hw_switch_state (1 = set, 0 = cleared)",
+	}, []string{"name"})
+	e.sdcardPresentMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sdcard_present",
+		Help:      "Whether an SD card is inserted (1) or not (0)",
 	})
-	e.mcPrintErrorCodeMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "mc_print_error_code",
-		Help: "Print Progress Error Code",
+	e.sdcardStateMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sdcard_state",
+		Help:      "State of the SD card (1 for the current state, 0 otherwise)",
+	}, []string{"state"})
+	e.totalLayersMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "total_layers",
+		Help:      "Total number of layers of the current print",
 	})
-	e.mcPrintStageMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "mc_print_stage",
-		Help: "Print Progress Stage",
+	e.layerProgressMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "layer_progress_ratio",
+		Help:      "Ratio of the current layer to the total number of layers",
 	})
-	e.mcPrintSubStageMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "mc_print_sub_stage",
-		Help: "Print Progress Sub Stage",
+	e.printETAMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "print_eta_timestamp_seconds",
+		Help:      "Unix timestamp at which the printer expects the current print to finish",
 	})
-	e.mcRemainingTimeMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "mc_remaining_time",
-		Help: "Print Progress Remaining Time in minutes",
+	e.estimatedETAMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "print_estimated_eta_timestamp_seconds",
+		Help:      "Unix timestamp at which the current print finishes, estimated from the observed layer rate",
 	})
-	e.nozzleTargetTemperMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "nozzle_target_temper",
-		Help: "Nozzle Target Temperature Metric",
+	e.jobInfoMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_info",
+		Help:      "File, subtask and identifiers of the current print job",
+	}, []string{"file", "subtask", "project_id", "task_id", "print_type"})
+	e.wifiSignalErrorsMetric = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wifi_signal_parse_errors_total",
+		Help:      "Number of Wi-Fi signal values that could not be parsed",
 	})
-	e.nozzleTemperMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "nozzle_temper",
-		Help: "Nozzle Temperature Metric",
+	e.wifiQualityMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wifi_signal_quality_percent",
+		Help:      "Wi-Fi signal quality in percent derived from the signal strength",
 	})
-	e.bedTargetTemperMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "bed_target_temper",
-		Help: "Bed target temperature metric",
+	e.wifiSignalMinMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wifi_signal_min_dbm",
+		Help:      "Minimum Wi-Fi signal strength in dBm over a rolling window",
+	}, []string{"window"})
+	e.wifiSignalAvgMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wifi_signal_avg_dbm",
+		Help:      "Average Wi-Fi signal strength in dBm over a rolling window",
+	}, []string{"window"})
+	e.decodeErrorsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decode_errors_total",
+		Help:      "Number of report fields that could not be decoded",
+	}, []string{"field"})
+	e.unknownCommandsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unknown_commands_total",
+		Help:      "Number of messages with a command the exporter does not handle",
+	}, []string{"command"})
+	e.commandResultsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_results_total",
+		Help:      "Number of command replies by result",
+	}, []string{"command", "result"})
+	e.pressureAdvanceMetric = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pressure_advance_k",
+		Help:      "Pressure advance (K) value of each stored flow dynamics calibration",
+	}, []string{"filament_id", "name", "nozzle_diameter"})
+	e.controlCommandsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "control_commands_total",
+		Help:      "Number of commands received by the control API by result",
+	}, []string{"command", "result"})
+	e.eventsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_total",
+		Help:      "Number of print events by type",
+	}, []string{"event"})
+	e.notificationsMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Number of event notification attempts by notifier and result",
+	}, []string{"notifier", "event", "result"})
+	e.proxyClientsMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "proxy_clients",
+		Help:      "Number of clients connected to the MQTT proxy",
 	})
-	e.bedTemperMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "bed_temper",
-		Help: "Bed temperature metric",
+	e.proxyMessagesMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proxy_messages_total",
+		Help:      "Number of messages relayed by the MQTT proxy by direction (report or request)",
+	}, []string{"direction"})
+	e.pushesMetric = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pushes_total",
+		Help:      "Number of metric snapshots pushed by result",
+	}, []string{"result"})
+	e.pushBufferedMetric = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "push_buffered_snapshots",
+		Help:      "Number of metric snapshots waiting to be pushed",
 	})
 }
 
 func (e *Exporter) ConnectToBroker() {
-	port := 8883
 	clientID := cmp.Or(os.Getenv("OVERRIDE_CLIENT_ID"), "bambulabs-prometheus-exporter")
 
 	opts := mqtt.NewClientOptions()
-	opts.AddBroker(fmt.Sprintf("ssl://%s:%d", e.config.IP, port))
+	opts.AddBroker(fmt.Sprintf("ssl://%s:%d", e.config.IP, e.config.Port))
 	opts.SetClientID(clientID)
 	opts.SetUsername(e.config.Username)
 	opts.SetPassword(e.config.Password)
@@ -184,74 +580,89 @@ func (e *Exporter) ConnectToBroker() {
 }
 
 func (e *Exporter) messagePubHandler(client mqtt.Client, msg mqtt.Message) {
-	s := msg.Payload()
-	data := BambuLabsX1C{}
-	err := json.Unmarshal([]byte(s), &data)
-	if err != nil {
-		fmt.Printf("Error unmarshalling JSON: %s\n", err)
-		return
+	if e.recorder != nil {
+		e.recorder.record(e.now(), msg.Topic(), msg.Payload())
 	}
-
-	if data.Print.Command != "push_status" {
-		fmt.Printf("Ignoring command: %s\n", data.Print.Command)
-		return
+	if p := e.proxy.Load(); p != nil {
+		p.report(msg.Payload())
 	}
+	e.dispatch(msg.Payload())
+}
 
-	e.layerNumberMetric.Set(float64(data.Print.LayerNum))
-	e.printErrorMetric.Set(float64(data.Print.PrintError))
-
-	wifi_signal, _ := strconv.ParseFloat(strings.ReplaceAll(data.Print.WifiSignal, "dBm", ""), 64)
-	e.wifiSignalMetric.Set(wifi_signal)
-
-	big_fan1_speed, _ := strconv.ParseFloat(data.Print.BigFan1Speed, 64)
-	e.bigFan1SpeedMetric.Set(big_fan1_speed)
-
-	big_fan2_speed, _ := strconv.ParseFloat(data.Print.BigFan2Speed, 64)
-	e.bigFan2SpeedMetric.Set(big_fan2_speed)
-
-	e.chamberTemperMetric.Set(data.Print.ChamberTemper)
-
-	cooling_fan_speed, _ := strconv.ParseFloat(data.Print.CoolingFanSpeed, 64)
-	e.coolingFanSpeedMetric.Set(cooling_fan_speed)
-
-	fail_reason, _ := strconv.ParseFloat(data.Print.FailReason, 64)
-	e.failReasonMetric.Set(fail_reason)
-
-	e.fanGearMetric.Set(float64(data.Print.FanGear))
-	e.mcPercentMetric.Set(float64(data.Print.McPercent))
+// updateStatus exports a push_status report.
+func (e *Exporter) updateStatus(data BambuLabsX1C) {
+	// Partial reports only include the fields that changed, so missing
+	// fields leave their gauges unchanged.
+	setNumber(e.layerNumberMetric, data.Print.LayerNum)
+	setNumber(e.printErrorMetric, data.Print.PrintError)
+	setNumber(e.chamberTemperMetric, data.Print.ChamberTemper)
+	setNumber(e.failReasonMetric, data.Print.FailReason)
+	setNumber(e.fanGearMetric, data.Print.FanGear)
+	setNumber(e.mcPercentMetric, data.Print.McPercent)
+	setNumber(e.mcPrintErrorCodeMetric, data.Print.McPrintErrorCode)
+	setNumber(e.mcPrintStageMetric, data.Print.McPrintStage)
+	setNumber(e.mcPrintSubStageMetric, data.Print.McPrintSubStage)
+	if data.Print.McRemainingTime.Present() {
+		e.mcRemainingTimeMetric.Set(data.Print.McRemainingTime.Float64() * 60)
+	}
+	setNumber(e.nozzleTemperMetric, data.Print.NozzleTemper)
+	setNumber(e.nozzleTargetTemperMetric, data.Print.NozzleTargetTemper)
+	setNumber(e.bedTargetTemperMetric, data.Print.BedTargetTemper)
+	setNumber(e.bedTemperMetric, data.Print.BedTemper)
+
+	e.updateUpgradeState(data)
+	e.updateSpeed(data)
+	e.updateWifi(data)
+	e.updateFans(data)
+	e.updateLights(data)
+	e.updateCamera(data)
+	e.updateXcam(data)
+	e.updateUpload(data)
+	e.updateFlags(data)
+	e.updateLayers(data)
+	e.updateJob(data)
 
-	mc_print_error_code, _ := strconv.ParseFloat(data.Print.McPrintErrorCode, 64)
-	e.mcPrintErrorCodeMetric.Set(mc_print_error_code)
+	for _, ams := range data.Print.Ams.Ams {
+		if ams.Humidity.Present() {
+			e.amsHumidityMetric.With(prometheus.Labels{"ams_number": ams.ID}).Set(ams.Humidity.Float64())
+		}
+		if ams.Temp.Present() {
+			e.amsTempMetric.With(prometheus.Labels{"ams_number": ams.ID}).Set(ams.Temp.Float64())
+		}
+		for _, tray := range ams.Tray {
+			e.setTrayFilament(ams.ID, tray.ID, tray.TrayType, tray.TrayColor)
+		}
+	}
 
-	mc_print_stage, _ := strconv.ParseFloat(data.Print.McPrintStage, 64)
-	e.mcPrintStageMetric.Set(mc_print_stage)
+	e.updateEvents(data)
+	if e.homeAssistant != nil {
+		e.homeAssistant.update(data, e.model, e.features())
+	}
 
-	e.mcPrintStageMetric.Set(float64(data.Print.McPrintSubStage))
-	e.mcRemainingTimeMetric.Set(float64(data.Print.McRemainingTime))
-	e.nozzleTemperMetric.Set(float64(data.Print.NozzleTemper))
-	e.nozzleTargetTemperMetric.Set(float64(data.Print.NozzleTargetTemper))
-	e.bedTargetTemperMetric.Set(data.Print.BedTargetTemper)
-	e.bedTemperMetric.Set(data.Print.BedTemper)
+	if data.Print.GcodeState != "" {
+		e.gcodeState = data.Print.GcodeState
+	}
+}
 
-	for _, ams := range data.Print.Ams.Ams {
-		humidity, _ := strconv.ParseFloat(ams.Humidity, 64)
-		e.amsHumidityMetric.With(prometheus.Labels{"ams_number": ams.ID}).Set(humidity)
+// setNumber sets a gauge to a report field that is present.
+func setNumber(gauge prometheus.Gauge, n Number) {
+	if n.Present() {
+		gauge.Set(n.Float64())
+	}
+}
 
-		temp, _ := strconv.ParseFloat(ams.Temp, 64)
-		e.amsTempMetric.With(prometheus.Labels{"ams_number": ams.ID}).Set(temp)
-		for _, tray := range ams.Tray {
-			baseLabels := prometheus.Labels{
-				"ams_number":  ams.ID,
-				"tray_number": tray.ID,
-			}
+// setTrayFilament exports the filament type and color loaded in an AMS tray.
+func (e *Exporter) setTrayFilament(amsID, trayID, trayType, trayColor string) {
+	baseLabels := prometheus.Labels{
+		"ams_number":  amsID,
+		"tray_number": trayID,
+	}
 
-			e.amsTypeMetric.DeletePartialMatch(baseLabels)
-			e.amsTypeMetric.MustCurryWith(baseLabels).With(prometheus.Labels{"tray_type": tray.TrayType}).Set(1)
+	e.amsTypeMetric.DeletePartialMatch(baseLabels)
+	e.amsTypeMetric.MustCurryWith(baseLabels).With(prometheus.Labels{"tray_type": trayType}).Set(1)
 
-			e.amsColorMetric.DeletePartialMatch(baseLabels)
-			e.amsColorMetric.MustCurryWith(baseLabels).With(prometheus.Labels{"tray_color": tray.TrayColor}).Set(1)
-		}
-	}
+	e.amsColorMetric.DeletePartialMatch(baseLabels)
+	e.amsColorMetric.MustCurryWith(baseLabels).With(prometheus.Labels{"tray_color": trayColor}).Set(1)
 }
 
 func (e *Exporter) buildConnectHandler() mqtt.OnConnectHandler {
@@ -259,6 +670,9 @@ func (e *Exporter) buildConnectHandler() mqtt.OnConnectHandler {
 		dt := time.Now()
 		fmt.Printf("Connected: %s\n", dt.String())
 		client.Subscribe(e.config.Topic, 1, nil).Wait()
+		if err := e.requestVersion(client); err != nil {
+			fmt.Printf("Error requesting version: %s\n", err)
+		}
 	}
 }
 
@@ -275,10 +689,19 @@ func (e *Exporter) newTLSConfig() *tls.Config {
 func (e *Exporter) StartHTTPServer() {
 	http.HandleFunc("/", e.home)
 	http.HandleFunc("/healthz", e.healthz)
-	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/metrics", e.metricsHandler())
+	e.registerControl(http.DefaultServeMux)
 	fmt.Printf("Listening http://127.0.0.1:9101\n")
 }
 
+func (e *Exporter) metricsHandler() http.Handler {
+	var gatherer prometheus.Gatherer = e.registry
+	if e.config.LegacyMetrics {
+		gatherer = prometheus.Gatherers{e.registry, legacyGatherer{e.registry}}
+	}
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
+
 func (e *Exporter) home(w http.ResponseWriter, r *http.Request) {
 	const body = `<html>
 				<head>
@@ -304,30 +727,44 @@ func (e *Exporter) GetConfig() Config {
 
 // BambuLabsX1C represents the structure of the MQTT message from BambuLabs printer
 type BambuLabsX1C struct {
+	Info struct {
+		Command    string `json:"command"`
+		SequenceID string `json:"sequence_id"`
+		Module     []struct {
+			Name        string `json:"name"`
+			ProjectName string `json:"project_name"`
+			SwVer       string `json:"sw_ver"`
+			HwVer       string `json:"hw_ver"`
+			Sn          string `json:"sn"`
+			LoaderVer   string `json:"loader_ver"`
+		} `json:"module"`
+		Result string `json:"result"`
+		Reason string `json:"reason"`
+	} `json:"info"`
 	Print struct {
 		Ams struct {
 			Ams []struct {
-				Humidity string `json:"humidity"`
+				Humidity Number `json:"humidity"`
 				ID       string `json:"id"`
-				Temp     string `json:"temp"`
+				Temp     Number `json:"temp"`
 				Tray     []struct {
-					BedTemp       string `json:"bed_temp"`
+					BedTemp       Number `json:"bed_temp"`
 					BedTempType   string `json:"bed_temp_type"`
-					DryingTemp    string `json:"drying_temp"`
-					DryingTime    string `json:"drying_time"`
+					DryingTemp    Number `json:"drying_temp"`
+					DryingTime    Number `json:"drying_time"`
 					ID            string `json:"id"`
-					NozzleTempMax string `json:"nozzle_temp_max"`
-					NozzleTempMin string `json:"nozzle_temp_min"`
-					Remain        int    `json:"remain"`
+					NozzleTempMax Number `json:"nozzle_temp_max"`
+					NozzleTempMin Number `json:"nozzle_temp_min"`
+					Remain        Number `json:"remain"`
 					TagUID        string `json:"tag_uid"`
 					TrayColor     string `json:"tray_color"`
-					TrayDiameter  string `json:"tray_diameter"`
+					TrayDiameter  Number `json:"tray_diameter"`
 					TrayIDName    string `json:"tray_id_name"`
 					TrayInfoIdx   string `json:"tray_info_idx"`
 					TraySubBrands string `json:"tray_sub_brands"`
 					TrayType      string `json:"tray_type"`
 					TrayUUID      string `json:"tray_uuid"`
-					TrayWeight    string `json:"tray_weight"`
+					TrayWeight    Number `json:"tray_weight"`
 					XcamInfo      string `json:"xcam_info"`
 				} `json:"tray"`
 			} `json:"ams"`
@@ -340,95 +777,95 @@ type BambuLabsX1C struct {
 			TrayReadDoneBits string `json:"tray_read_done_bits"`
 			TrayReadingBits  string `json:"tray_reading_bits"`
 			TrayTar          string `json:"tray_tar"`
-			Version          int    `json:"version"`
+			Version          Number `json:"version"`
 		} `json:"ams"`
-		AmsRfidStatus           int     `json:"ams_rfid_status"`
-		AmsStatus               int     `json:"ams_status"`
-		BedTargetTemper         float64 `json:"bed_target_temper"`
-		BedTemper               float64 `json:"bed_temper"`
-		BigFan1Speed            string  `json:"big_fan1_speed"`
-		BigFan2Speed            string  `json:"big_fan2_speed"`
-		ChamberTemper           float64 `json:"chamber_temper"`
-		Command                 string  `json:"command"`
-		CoolingFanSpeed         string  `json:"cooling_fan_speed"`
-		FailReason              string  `json:"fail_reason"`
-		FanGear                 int     `json:"fan_gear"`
-		ForceUpgrade            bool    `json:"force_upgrade"`
-		GcodeFile               string  `json:"gcode_file"`
-		GcodeFilePreparePercent string  `json:"gcode_file_prepare_percent"`
-		GcodeStartTime          string  `json:"gcode_start_time"`
-		GcodeState              string  `json:"gcode_state"`
-		HeatbreakFanSpeed       string  `json:"heatbreak_fan_speed"`
-		Hms                     []any   `json:"hms"`
-		HomeFlag                int     `json:"home_flag"`
-		HwSwitchState           int     `json:"hw_switch_state"`
+		AmsRfidStatus           Number     `json:"ams_rfid_status"`
+		AmsStatus               Number     `json:"ams_status"`
+		BedTargetTemper         Number     `json:"bed_target_temper"`
+		BedTemper               Number     `json:"bed_temper"`
+		BigFan1Speed            Number     `json:"big_fan1_speed"`
+		BigFan2Speed            Number     `json:"big_fan2_speed"`
+		ChamberTemper           Number     `json:"chamber_temper"`
+		Command                 string     `json:"command"`
+		CoolingFanSpeed         Number     `json:"cooling_fan_speed"`
+		FailReason              Number     `json:"fail_reason"`
+		FanGear                 Number     `json:"fan_gear"`
+		ForceUpgrade            bool       `json:"force_upgrade"`
+		GcodeFile               string     `json:"gcode_file"`
+		GcodeFilePreparePercent Number     `json:"gcode_file_prepare_percent"`
+		GcodeStartTime          string     `json:"gcode_start_time"`
+		GcodeState              string     `json:"gcode_state"`
+		HeatbreakFanSpeed       Number     `json:"heatbreak_fan_speed"`
+		Hms                     []hmsEntry `json:"hms"`
+		HomeFlag                Number     `json:"home_flag"`
+		HwSwitchState           Number     `json:"hw_switch_state"`
 		Ipcam                   struct {
 			IpcamDev    string `json:"ipcam_dev"`
 			IpcamRecord string `json:"ipcam_record"`
 			Resolution  string `json:"resolution"`
 			Timelapse   string `json:"timelapse"`
 		} `json:"ipcam"`
-		LayerNum     int    `json:"layer_num"`
+		LayerNum     Number `json:"layer_num"`
 		Lifecycle    string `json:"lifecycle"`
 		LightsReport []struct {
 			Mode string `json:"mode"`
 			Node string `json:"node"`
 		} `json:"lights_report"`
-		Maintain            int     `json:"maintain"`
-		McPercent           int     `json:"mc_percent"`
-		McPrintErrorCode    string  `json:"mc_print_error_code"`
-		McPrintStage        string  `json:"mc_print_stage"`
-		McPrintSubStage     int     `json:"mc_print_sub_stage"`
-		McRemainingTime     int     `json:"mc_remaining_time"`
-		MessProductionState string  `json:"mess_production_state"`
-		NozzleTargetTemper  float64 `json:"nozzle_target_temper"`
-		NozzleTemper        float64 `json:"nozzle_temper"`
+		Maintain            Number `json:"maintain"`
+		McPercent           Number `json:"mc_percent"`
+		McPrintErrorCode    Number `json:"mc_print_error_code"`
+		McPrintStage        Number `json:"mc_print_stage"`
+		McPrintSubStage     Number `json:"mc_print_sub_stage"`
+		McRemainingTime     Number `json:"mc_remaining_time"`
+		MessProductionState string `json:"mess_production_state"`
+		NozzleTargetTemper  Number `json:"nozzle_target_temper"`
+		NozzleTemper        Number `json:"nozzle_temper"`
 		Online              struct {
 			Ahb  bool `json:"ahb"`
 			Rfid bool `json:"rfid"`
 		} `json:"online"`
-		PrintError       int    `json:"print_error"`
-		PrintGcodeAction int    `json:"print_gcode_action"`
-		PrintRealAction  int    `json:"print_real_action"`
-		PrintType        string `json:"print_type"`
-		ProfileID        string `json:"profile_id"`
-		ProjectID        string `json:"project_id"`
-		Sdcard           bool   `json:"sdcard"`
-		SequenceID       string `json:"sequence_id"`
-		SpdLvl           int    `json:"spd_lvl"`
-		SpdMag           int    `json:"spd_mag"`
-		Stg              []int  `json:"stg"`
-		StgCur           int    `json:"stg_cur"`
-		SubtaskID        string `json:"subtask_id"`
-		SubtaskName      string `json:"subtask_name"`
-		TaskID           string `json:"task_id"`
-		TotalLayerNum    int    `json:"total_layer_num"`
+		PrintError       Number   `json:"print_error"`
+		PrintGcodeAction Number   `json:"print_gcode_action"`
+		PrintRealAction  Number   `json:"print_real_action"`
+		PrintType        string   `json:"print_type"`
+		ProfileID        string   `json:"profile_id"`
+		ProjectID        string   `json:"project_id"`
+		Sdcard           bool     `json:"sdcard"`
+		SequenceID       string   `json:"sequence_id"`
+		SpdLvl           Number   `json:"spd_lvl"`
+		SpdMag           Number   `json:"spd_mag"`
+		Stg              []Number `json:"stg"`
+		StgCur           Number   `json:"stg_cur"`
+		SubtaskID        string   `json:"subtask_id"`
+		SubtaskName      string   `json:"subtask_name"`
+		TaskID           string   `json:"task_id"`
+		TotalLayerNum    Number   `json:"total_layer_num"`
 		UpgradeState     struct {
 			AhbNewVersionNumber string `json:"ahb_new_version_number"`
 			AmsNewVersionNumber string `json:"ams_new_version_number"`
 			ConsistencyRequest  bool   `json:"consistency_request"`
-			DisState            int    `json:"dis_state"`
-			ErrCode             int    `json:"err_code"`
+			DisState            Number `json:"dis_state"`
+			ErrCode             Number `json:"err_code"`
 			ForceUpgrade        bool   `json:"force_upgrade"`
 			Message             string `json:"message"`
 			Module              string `json:"module"`
-			NewVersionState     int    `json:"new_version_state"`
+			NewVersionState     Number `json:"new_version_state"`
 			OtaNewVersionNumber string `json:"ota_new_version_number"`
-			Progress            string `json:"progress"`
-			SequenceID          int    `json:"sequence_id"`
+			Progress            Number `json:"progress"`
+			SequenceID          Number `json:"sequence_id"`
 			Status              string `json:"status"`
 		} `json:"upgrade_state"`
 		Upload struct {
-			FileSize      int    `json:"file_size"`
-			FinishSize    int    `json:"finish_size"`
+			FileSize      Number `json:"file_size"`
+			FinishSize    Number `json:"finish_size"`
 			Message       string `json:"message"`
 			OssURL        string `json:"oss_url"`
-			Progress      int    `json:"progress"`
+			Progress      Number `json:"progress"`
 			SequenceID    string `json:"sequence_id"`
-			Speed         int    `json:"speed"`
+			Speed         Number `json:"speed"`
 			Status        string `json:"status"`
 			TaskID        string `json:"task_id"`
-			TimeRemaining int    `json:"time_remaining"`
+			TimeRemaining Number `json:"time_remaining"`
 			TroubleID     string `json:"trouble_id"`
 		} `json:"upload"`
 		WifiSignal string `json:"wifi_signal"`
@@ -441,6 +878,6 @@ type BambuLabsX1C struct {
 			PrintingMonitor          bool   `json:"printing_monitor"`
 			SpaghettiDetector        bool   `json:"spaghetti_detector"`
 		} `json:"xcam"`
-		XcamStatus string `json:"xcam_status"`
+		XcamStatus Number `json:"xcam_status"`
 	} `json:"print"`
-}
\ No newline at end of file
+}
@@ -11,7 +11,6 @@ import (
 
 	"github.com/eclipse/paho.mqtt.golang"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
@@ -119,14 +118,14 @@ func TestExporterHTTPEndpoints(t *testing.T) {
 			}
 
 			rr := httptest.NewRecorder()
-			
+
 			switch tt.path {
 			case "/":
 				exporter.home(rr, req)
 			case "/healthz":
 				exporter.healthz(rr, req)
 			case "/metrics":
-				promhttp.Handler().ServeHTTP(rr, req)
+				exporter.metricsHandler().ServeHTTP(rr, req)
 			}
 
 			if rr.Code != tt.expectedStatus {
@@ -350,6 +349,30 @@ func TestExporterMessageHandlerWrongCommand(t *testing.T) {
 	exporter.messagePubHandler(mockClient, mockMsg)
 }
 
+func TestExporterPartialReport(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	full := `{"print": {"command": "push_status", "layer_num": 10, "mc_percent": 25, "mc_remaining_time": 30, "nozzle_temper": 220, "bed_temper": 55, "ams": {"ams": [{"id": "0", "humidity": "3", "temp": "24"}]}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(full)})
+	partial := `{"print": {"command": "push_status", "nozzle_temper": 221, "ams": {"ams": [{"id": "0"}]}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(partial)})
+
+	expected := map[prometheus.Gauge]float64{
+		exporter.nozzleTemperMetric:                     221,
+		exporter.bedTemperMetric:                        55,
+		exporter.layerNumberMetric:                      10,
+		exporter.mcPercentMetric:                        25,
+		exporter.mcRemainingTimeMetric:                  1800,
+		exporter.amsHumidityMetric.WithLabelValues("0"): 3,
+		exporter.amsTempMetric.WithLabelValues("0"):     24,
+	}
+	for gauge, value := range expected {
+		if actual := testutil.ToFloat64(gauge); actual != value {
+			t.Errorf("Expected %s to be %v after a partial report, got %v", gauge.Desc(), value, actual)
+		}
+	}
+}
+
 func TestBambuLabsX1CStruct(t *testing.T) {
 	sampleJSON := `{
 		"print": {
@@ -399,51 +422,51 @@ func TestBambuLabsX1CStruct(t *testing.T) {
 	if data.Print.Command != "push_status" {
 		t.Errorf("Expected command 'push_status', got '%s'", data.Print.Command)
 	}
-	if data.Print.LayerNum != 5 {
-		t.Errorf("Expected layer_num 5, got %d", data.Print.LayerNum)
+	if data.Print.LayerNum.Int() != 5 {
+		t.Errorf("Expected layer_num 5, got %d", data.Print.LayerNum.Int())
 	}
-	if data.Print.PrintError != 0 {
-		t.Errorf("Expected print_error 0, got %d", data.Print.PrintError)
+	if data.Print.PrintError.Int() != 0 {
+		t.Errorf("Expected print_error 0, got %d", data.Print.PrintError.Int())
 	}
 	if data.Print.WifiSignal != "-45dBm" {
 		t.Errorf("Expected wifi_signal '-45dBm', got '%s'", data.Print.WifiSignal)
 	}
-	if data.Print.ChamberTemper != 25.5 {
-		t.Errorf("Expected chamber_temper 25.5, got %f", data.Print.ChamberTemper)
+	if data.Print.ChamberTemper.Float64() != 25.5 {
+		t.Errorf("Expected chamber_temper 25.5, got %f", data.Print.ChamberTemper.Float64())
 	}
-	if data.Print.FanGear != 2 {
-		t.Errorf("Expected fan_gear 2, got %d", data.Print.FanGear)
+	if data.Print.FanGear.Int() != 2 {
+		t.Errorf("Expected fan_gear 2, got %d", data.Print.FanGear.Int())
 	}
-	if data.Print.McPercent != 25 {
-		t.Errorf("Expected mc_percent 25, got %d", data.Print.McPercent)
+	if data.Print.McPercent.Int() != 25 {
+		t.Errorf("Expected mc_percent 25, got %d", data.Print.McPercent.Int())
 	}
-	if data.Print.NozzleTemper != 200.0 {
-		t.Errorf("Expected nozzle_temper 200.0, got %f", data.Print.NozzleTemper)
+	if data.Print.NozzleTemper.Float64() != 200.0 {
+		t.Errorf("Expected nozzle_temper 200.0, got %f", data.Print.NozzleTemper.Float64())
 	}
-	if data.Print.NozzleTargetTemper != 210.0 {
-		t.Errorf("Expected nozzle_target_temper 210.0, got %f", data.Print.NozzleTargetTemper)
+	if data.Print.NozzleTargetTemper.Float64() != 210.0 {
+		t.Errorf("Expected nozzle_target_temper 210.0, got %f", data.Print.NozzleTargetTemper.Float64())
 	}
 
 	// Test AMS data
 	if len(data.Print.Ams.Ams) != 1 {
 		t.Errorf("Expected 1 AMS, got %d", len(data.Print.Ams.Ams))
 	}
-	
+
 	ams := data.Print.Ams.Ams[0]
 	if ams.ID != "0" {
 		t.Errorf("Expected AMS ID '0', got '%s'", ams.ID)
 	}
-	if ams.Humidity != "45.2" {
-		t.Errorf("Expected humidity '45.2', got '%s'", ams.Humidity)
+	if ams.Humidity.Float64() != 45.2 {
+		t.Errorf("Expected humidity 45.2, got %f", ams.Humidity.Float64())
 	}
-	if ams.Temp != "23.1" {
-		t.Errorf("Expected temp '23.1', got '%s'", ams.Temp)
+	if ams.Temp.Float64() != 23.1 {
+		t.Errorf("Expected temp 23.1, got %f", ams.Temp.Float64())
 	}
-	
+
 	if len(ams.Tray) != 1 {
 		t.Errorf("Expected 1 tray, got %d", len(ams.Tray))
 	}
-	
+
 	tray := ams.Tray[0]
 	if tray.ID != "0" {
 		t.Errorf("Expected tray ID '0', got '%s'", tray.ID)
@@ -456,6 +479,35 @@ func TestBambuLabsX1CStruct(t *testing.T) {
 	}
 }
 
+// newTestExporter builds an exporter from the standard test environment plus
+// any overrides in env.
+func newTestExporter(t *testing.T, env map[string]string) *Exporter {
+	t.Helper()
+
+	t.Setenv("BAMBULABS_IP", "192.168.1.100")
+	t.Setenv("BAMBULABS_USERNAME", "testuser")
+	t.Setenv("BAMBULABS_PASSWORD", "testpass")
+	t.Setenv("BAMBULABS_TOPIC", "device/test123/report")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	return NewExporter()
+}
+
+// scrape returns the body of the exporter's /metrics endpoint.
+func scrape(t *testing.T, exporter *Exporter) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	exporter.metricsHandler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	return rr.Body.String()
+}
+
 // Mock implementations for testing
 type mockMessage struct {
 	payload []byte
@@ -488,7 +540,14 @@ func (m *mockMessage) Payload() []byte {
 func (m *mockMessage) Ack() {
 }
 
-type mockClient struct{}
+type mockClient struct {
+	published []mockPublish
+}
+
+type mockPublish struct {
+	topic   string
+	payload []byte
+}
 
 func (m *mockClient) IsConnected() bool {
 	return true
@@ -506,6 +565,7 @@ func (m *mockClient) Disconnect(quiesce uint) {
 }
 
 func (m *mockClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	m.published = append(m.published, mockPublish{topic: topic, payload: payload.([]byte)})
 	return &mockToken{}
 }
 
@@ -549,4 +609,4 @@ func (m *mockToken) Done() <-chan struct{} {
 
 func (m *mockToken) Error() error {
 	return nil
-}
\ No newline at end of file
+}
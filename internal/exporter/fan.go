@@ -0,0 +1,61 @@
+package exporter
+
+import "math"
+
+// Fan names used as the fan label of bambulabs_fan_speed_percent.
+const (
+	fanPartCooling = "part_cooling"
+	fanAux         = "aux"
+	fanChamber     = "chamber"
+	fanHeatbreak   = "heatbreak"
+)
+
+// fanGearPercent converts a fan gear as reported in the *_fan_speed fields
+// (0-15) to a percentage. ok is false when the field is missing or invalid.
+func fanGearPercent(gear Number) (percent float64, ok bool) {
+	if !gear.Present() {
+		return 0, false
+	}
+	return math.Round(gear.Float64() / 15 * 100), true
+}
+
+// decodeFanGear splits the packed fan_gear field into the speeds of the part
+// cooling, aux and chamber fans in percent. Each fan uses one byte (0-255):
+// bits 0-7 for the part cooling fan, 8-15 for the aux fan and 16-23 for the
+// chamber fan.
+func decodeFanGear(gear int) (partCooling, aux, chamber float64) {
+	percent := func(shift uint) float64 {
+		return math.Round(float64((gear>>shift)&0xFF) / 255 * 100)
+	}
+	return percent(0), percent(8), percent(16)
+}
+
+// updateFans exports the fan speeds in percent. The packed fan_gear field has
+// a finer resolution than the individual gear fields, so it takes precedence
+// when reported.
+func (e *Exporter) updateFans(data BambuLabsX1C) {
+	speeds := map[string]Number{
+		fanPartCooling: data.Print.CoolingFanSpeed,
+		fanAux:         data.Print.BigFan1Speed,
+		fanChamber:     data.Print.BigFan2Speed,
+		fanHeatbreak:   data.Print.HeatbreakFanSpeed,
+	}
+
+	percents := map[string]float64{}
+	for fan, gear := range speeds {
+		if percent, ok := fanGearPercent(gear); ok {
+			percents[fan] = percent
+		}
+	}
+	if gear := data.Print.FanGear.Int(); gear != 0 {
+		percents[fanPartCooling], percents[fanAux], percents[fanChamber] = decodeFanGear(gear)
+	}
+
+	features := e.features()
+	for fan, percent := range percents {
+		if (fan == fanAux && !features.auxFan) || (fan == fanChamber && !features.chamberFan) {
+			continue
+		}
+		e.fanSpeedMetric.WithLabelValues(fan).Set(percent)
+	}
+}
@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeFanGear(t *testing.T) {
+	tests := []struct {
+		gear                      int
+		partCooling, aux, chamber float64
+	}{
+		{0, 0, 0, 0},
+		{0xFF, 100, 0, 0},
+		{45823, 100, 70, 0},
+		{0xFF0000, 0, 0, 100},
+		{0x808080, 50, 50, 50},
+	}
+
+	for _, tt := range tests {
+		partCooling, aux, chamber := decodeFanGear(tt.gear)
+		if partCooling != tt.partCooling || aux != tt.aux || chamber != tt.chamber {
+			t.Errorf("decodeFanGear(%d) = %v, %v, %v, expected %v, %v, %v",
+				tt.gear, partCooling, aux, chamber, tt.partCooling, tt.aux, tt.chamber)
+		}
+	}
+}
+
+func TestFanGearPercent(t *testing.T) {
+	tests := []struct {
+		gear     string
+		expected float64
+		ok       bool
+	}{
+		{"0", 0, true},
+		{"15", 100, true},
+		{"11", 73, true},
+		{"", 0, false},
+		{"fast", 0, false},
+	}
+
+	for _, tt := range tests {
+		var gear Number
+		if err := json.Unmarshal([]byte(strconv.Quote(tt.gear)), &gear); err != nil {
+			t.Fatalf("Failed to unmarshal %q: %v", tt.gear, err)
+		}
+		percent, ok := fanGearPercent(gear)
+		if percent != tt.expected || ok != tt.ok {
+			t.Errorf("fanGearPercent(%q) = %v, %v, expected %v, %v", tt.gear, percent, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestFanSpeedMetrics(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_LEGACY_METRICS": "true"})
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"cooling_fan_speed": "15",
+			"big_fan1_speed": "6",
+			"big_fan2_speed": "0",
+			"heatbreak_fan_speed": "15"
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	expected := map[string]float64{
+		fanPartCooling: 100,
+		fanAux:         40,
+		fanChamber:     0,
+		fanHeatbreak:   100,
+	}
+	for fan, percent := range expected {
+		if value := testutil.ToFloat64(exporter.fanSpeedMetric.WithLabelValues(fan)); value != percent {
+			t.Errorf("Expected %s fan speed %v, got %v", fan, percent, value)
+		}
+	}
+
+	body := scrape(t, exporter)
+	for _, line := range []string{"\ncooling_fan_speed 15\n", "\nbig_fan1_speed 6\n", "\nbig_fan2_speed 0\n"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected legacy metrics to contain %q", line)
+		}
+	}
+
+	// The packed fan_gear field takes precedence over the gear fields.
+	report = `{
+		"print": {
+			"command": "push_status",
+			"cooling_fan_speed": "15",
+			"big_fan1_speed": "11",
+			"big_fan2_speed": "0",
+			"fan_gear": 45823
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.fanSpeedMetric.WithLabelValues(fanAux)); value != 70 {
+		t.Errorf("Expected aux fan speed 70, got %v", value)
+	}
+}
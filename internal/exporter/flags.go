@@ -0,0 +1,97 @@
+package exporter
+
+// The printer packs many booleans into the home_flag and hw_switch_state
+// integers of push_status reports. Each bit is described by a flagBit and
+// exported as a bambulabs_flag series named after it, so names must be unique
+// across both fields. Bits that are not listed are ignored.
+
+// flagBit names a single bit of a packed status field.
+type flagBit struct {
+	name string
+	mask uint32
+}
+
+// homeFlags describes the bits of the home_flag field. Bits 8 and 9 hold the
+// SD card state and are decoded by sdcardState instead.
+var homeFlags = []flagBit{
+	{"x_axis_homed", 1 << 0},
+	{"y_axis_homed", 1 << 1},
+	{"z_axis_homed", 1 << 2},
+	{"voltage_220v", 1 << 3},
+	{"xcam_auto_recovery_step_loss", 1 << 4},
+	{"camera_recording", 1 << 5},
+	{"ams_calibrate_remaining", 1 << 7},
+	{"ams_auto_switch", 1 << 10},
+	{"xcam_allow_prompt_sound", 1 << 17},
+	{"wired_network", 1 << 18},
+	{"filament_tangle_detect_supported", 1 << 19},
+	{"filament_tangle_detect_enabled", 1 << 20},
+	{"motor_calibration_supported", 1 << 21},
+	{"door_open", 1 << 23},
+	{"plus_installed", 1 << 26},
+	{"plus_supported", 1 << 27},
+}
+
+// hwSwitchFlags describes the bits of the hw_switch_state field.
+var hwSwitchFlags = []flagBit{
+	{"filament_present", 1 << 0},
+}
+
+// sdcardStates names the values of bits 8 and 9 of home_flag.
+var sdcardStates = map[uint32]string{
+	0: "none",
+	1: "normal",
+	2: "abnormal",
+	3: "readonly",
+}
+
+// decodeFlags returns the state of each named bit in value.
+func decodeFlags(value int, bits []flagBit) map[string]bool {
+	flags := make(map[string]bool, len(bits))
+	for _, bit := range bits {
+		flags[bit.name] = uint32(value)&bit.mask != 0
+	}
+	return flags
+}
+
+// sdcardState returns the SD card state packed into home_flag.
+func sdcardState(homeFlag int) string {
+	return sdcardStates[(uint32(homeFlag)>>8)&0x3]
+}
+
+// updateFlags exports the bits of home_flag and hw_switch_state and the SD
+// card state. Partial reports omit both fields, so nothing is updated when
+// home_flag is missing. hw_switch_state is only trusted alongside it.
+func (e *Exporter) updateFlags(data BambuLabsX1C) {
+	if !data.Print.HomeFlag.Present() {
+		return
+	}
+
+	e.setFlags(decodeFlags(data.Print.HomeFlag.Int(), homeFlags))
+	e.setFlags(decodeFlags(data.Print.HwSwitchState.Int(), hwSwitchFlags))
+
+	state := sdcardState(data.Print.HomeFlag.Int())
+	for _, name := range sdcardStates {
+		value := 0.0
+		if name == state {
+			value = 1
+		}
+		e.sdcardStateMetric.WithLabelValues(name).Set(value)
+	}
+
+	present := 0.0
+	if data.Print.Sdcard || state != "none" {
+		present = 1
+	}
+	e.sdcardPresentMetric.Set(present)
+}
+
+func (e *Exporter) setFlags(flags map[string]bool) {
+	for name, set := range flags {
+		value := 0.0
+		if set {
+			value = 1
+		}
+		e.flagMetric.WithLabelValues(name).Set(value)
+	}
+}
@@ -0,0 +1,153 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeHomeFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		homeFlag int
+		set      []string
+	}{
+		{
+			name:     "nothing set",
+			homeFlag: 0,
+		},
+		{
+			name:     "axes homed",
+			homeFlag: 0x7,
+			set:      []string{"x_axis_homed", "y_axis_homed", "z_axis_homed"},
+		},
+		{
+			name:     "door open with tangle detection",
+			homeFlag: 1<<23 | 1<<19 | 1<<20,
+			set:      []string{"door_open", "filament_tangle_detect_supported", "filament_tangle_detect_enabled"},
+		},
+		{
+			name:     "sdcard bits are not flags",
+			homeFlag: 0x300,
+		},
+		{
+			name:     "auto recovery and prompt sound",
+			homeFlag: 1<<4 | 1<<17,
+			set:      []string{"xcam_auto_recovery_step_loss", "xcam_allow_prompt_sound"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := decodeFlags(tt.homeFlag, homeFlags)
+			if len(flags) != len(homeFlags) {
+				t.Errorf("Expected %d flags, got %d", len(homeFlags), len(flags))
+			}
+
+			expected := map[string]bool{}
+			for _, name := range tt.set {
+				expected[name] = true
+			}
+			for name, set := range flags {
+				if set != expected[name] {
+					t.Errorf("Expected %s to be %v, got %v", name, expected[name], set)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeHwSwitchFlags(t *testing.T) {
+	tests := []struct {
+		hwSwitchState int
+		expected      bool
+	}{
+		{0, false},
+		{1, true},
+		{2, false},
+		{3, true},
+	}
+
+	for _, tt := range tests {
+		flags := decodeFlags(tt.hwSwitchState, hwSwitchFlags)
+		if flags["filament_present"] != tt.expected {
+			t.Errorf("decodeFlags(%d) filament_present = %v, expected %v", tt.hwSwitchState, flags["filament_present"], tt.expected)
+		}
+	}
+}
+
+func TestFlagNamesUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, bits := range [][]flagBit{homeFlags, hwSwitchFlags} {
+		for _, bit := range bits {
+			if seen[bit.name] {
+				t.Errorf("Duplicate flag name %s", bit.name)
+			}
+			seen[bit.name] = true
+		}
+	}
+}
+
+func TestSdcardState(t *testing.T) {
+	tests := []struct {
+		homeFlag int
+		expected string
+	}{
+		{0, "none"},
+		{0x100, "normal"},
+		{0x200, "abnormal"},
+		{0x300, "readonly"},
+		{0x7 | 0x100 | 1<<23, "normal"},
+	}
+
+	for _, tt := range tests {
+		if state := sdcardState(tt.homeFlag); state != tt.expected {
+			t.Errorf("sdcardState(%#x) = %s, expected %s", tt.homeFlag, state, tt.expected)
+		}
+	}
+}
+
+func TestFlagMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{"print": {"command": "push_status", "sdcard": true, "home_flag": 8389895, "hw_switch_state": 1}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.flagMetric.WithLabelValues("door_open")); value != 1 {
+		t.Errorf("Expected door_open 1, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.flagMetric.WithLabelValues("wired_network")); value != 0 {
+		t.Errorf("Expected wired_network 0, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.flagMetric.WithLabelValues("filament_present")); value != 1 {
+		t.Errorf("Expected filament_present 1, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.sdcardPresentMetric); value != 1 {
+		t.Errorf("Expected sdcard present, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.sdcardStateMetric.WithLabelValues("normal")); value != 1 {
+		t.Errorf("Expected sdcard state normal, got %v", value)
+	}
+
+	report = `{"print": {"command": "push_status", "sdcard": false, "home_flag": 7}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.sdcardPresentMetric); value != 0 {
+		t.Errorf("Expected sdcard missing, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.sdcardStateMetric.WithLabelValues("none")); value != 1 {
+		t.Errorf("Expected sdcard state none, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.flagMetric.WithLabelValues("door_open")); value != 0 {
+		t.Errorf("Expected door_open 0, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.flagMetric.WithLabelValues("filament_present")); value != 0 {
+		t.Errorf("Expected filament_present 0, got %v", value)
+	}
+
+	// Partial reports keep the previous flags.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status"}}`)})
+	if value := testutil.ToFloat64(exporter.flagMetric.WithLabelValues("z_axis_homed")); value != 1 {
+		t.Errorf("Expected z_axis_homed to stay 1, got %v", value)
+	}
+}
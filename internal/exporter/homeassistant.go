@@ -0,0 +1,262 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// haSensor describes a Home Assistant sensor. Its state is the value of key
+// in the JSON document published to the state topic.
+type haSensor struct {
+	key         string
+	name        string
+	unit        string
+	deviceClass string
+	stateClass  string
+	// attributes is the state key of a value exposed as the color attribute.
+	attributes string
+}
+
+// haSensors are the printer sensors published to Home Assistant. requires
+// limits a sensor to models with the hardware, value returns its state and
+// whether the report included it.
+var haSensors = []struct {
+	haSensor
+	requires func(modelFeatures) bool
+	value    func(BambuLabsX1C) (any, bool)
+}{
+	{haSensor: haSensor{key: "state", name: "State"}, value: func(d BambuLabsX1C) (any, bool) { return haText(d.Print.GcodeState) }},
+	{haSensor: haSensor{key: "job", name: "Job"}, value: func(d BambuLabsX1C) (any, bool) { return haText(d.Print.SubtaskName) }},
+	{haSensor: haSensor{key: "progress", name: "Progress", unit: "%", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.McPercent) }},
+	{haSensor: haSensor{key: "remaining_time", name: "Remaining time", unit: "min", deviceClass: "duration", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.McRemainingTime) }},
+	{haSensor: haSensor{key: "layer", name: "Layer", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.LayerNum) }},
+	{haSensor: haSensor{key: "total_layers", name: "Total layers", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.TotalLayerNum) }},
+	{haSensor: haSensor{key: "nozzle_temperature", name: "Nozzle temperature", unit: "°C", deviceClass: "temperature", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.NozzleTemper) }},
+	{haSensor: haSensor{key: "nozzle_target_temperature", name: "Nozzle target temperature", unit: "°C", deviceClass: "temperature", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.NozzleTargetTemper) }},
+	{haSensor: haSensor{key: "bed_temperature", name: "Bed temperature", unit: "°C", deviceClass: "temperature", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.BedTemper) }},
+	{haSensor: haSensor{key: "bed_target_temperature", name: "Bed target temperature", unit: "°C", deviceClass: "temperature", stateClass: "measurement"}, value: func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.BedTargetTemper) }},
+	{
+		haSensor: haSensor{key: "chamber_temperature", name: "Chamber temperature", unit: "°C", deviceClass: "temperature", stateClass: "measurement"},
+		requires: func(f modelFeatures) bool { return f.chamberTemperature },
+		value:    func(d BambuLabsX1C) (any, bool) { return haNumber(d.Print.ChamberTemper) },
+	},
+}
+
+func haNumber(n Number) (any, bool) {
+	return n.Float64(), n.Present()
+}
+
+func haText(s string) (any, bool) {
+	return s, s != ""
+}
+
+// homeAssistant republishes the printer state to a separate MQTT broker
+// following the Home Assistant MQTT discovery conventions. The state is kept
+// as one retained JSON document so Home Assistant sees the full state after a
+// restart even though the printer only sends changed fields.
+type homeAssistant struct {
+	client mqtt.Client
+	prefix string
+	serial string
+
+	mu         sync.Mutex
+	model      PrinterModel
+	state      map[string]any
+	sensors    map[string]haSensor
+	discovered map[string]bool
+}
+
+// ConnectToHomeAssistant connects to the broker in
+// BAMBULABS_HOMEASSISTANT_BROKER. It does nothing when it is not set. The
+// serial number in BAMBULABS_TOPIC is required as it identifies the printer
+// in topics and the client ID.
+func (e *Exporter) ConnectToHomeAssistant() {
+	if e.config.HomeassistantBroker == "" {
+		return
+	}
+	if e.serial() == "" {
+		panic("BAMBULABS_HOMEASSISTANT_BROKER requires BAMBULABS_TOPIC with the serial number, e.g. device/<serial>/report")
+	}
+
+	h := &homeAssistant{
+		prefix:     e.config.HomeassistantDiscoveryPrefix,
+		serial:     e.serial(),
+		model:      e.model,
+		state:      map[string]any{},
+		sensors:    map[string]haSensor{},
+		discovered: map[string]bool{},
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(e.config.HomeassistantBroker)
+	opts.SetClientID("bambulabs-exporter-" + h.serial)
+	opts.SetUsername(e.config.HomeassistantUsername)
+	opts.SetPassword(e.config.HomeassistantPassword)
+	opts.SetAutoReconnect(true)
+	opts.SetWill(h.availabilityTopic(), "offline", 1, true)
+	opts.OnConnect = func(client mqtt.Client) {
+		fmt.Printf("Connected to Home Assistant broker %s\n", e.config.HomeassistantBroker)
+		h.online()
+	}
+	opts.OnConnectionLost = func(client mqtt.Client, err error) {
+		fmt.Printf("Home Assistant connection lost: %+v\n", err)
+	}
+
+	h.client = mqtt.NewClient(opts)
+	e.homeAssistant = h
+	if token := h.client.Connect(); token.Wait() && token.Error() != nil {
+		panic(token.Error())
+	}
+}
+
+// baseTopic is the topic all state of the printer is published under.
+func (h *homeAssistant) baseTopic() string {
+	return "bambulabs/" + h.serial
+}
+
+func (h *homeAssistant) availabilityTopic() string {
+	return h.baseTopic() + "/availability"
+}
+
+func (h *homeAssistant) stateTopic() string {
+	return h.baseTopic() + "/state"
+}
+
+// online marks the printer available and republishes discovery and state
+// after a (re)connect, as the broker may have lost retained messages.
+func (h *homeAssistant) online() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.publish(h.availabilityTopic(), "online")
+	h.discovered = map[string]bool{}
+	if len(h.state) > 0 {
+		h.discoverAll()
+		h.publishState()
+	}
+}
+
+// update merges a push_status report into the state, announces sensors seen
+// for the first time and publishes the state.
+func (h *homeAssistant) update(data BambuLabsX1C, model PrinterModel, features modelFeatures) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if model != h.model {
+		// The device model is part of every discovery config.
+		h.model = model
+		h.discovered = map[string]bool{}
+	}
+
+	for _, s := range haSensors {
+		if s.requires != nil && !s.requires(features) {
+			continue
+		}
+		if value, ok := s.value(data); ok {
+			h.set(s.haSensor, value)
+		}
+	}
+
+	for _, ams := range data.Print.Ams.Ams {
+		key, name := "ams_"+ams.ID, "AMS "+ams.ID
+		if features.amsEnvironment && ams.Humidity.Present() {
+			h.set(haSensor{key: key + "_humidity", name: name + " humidity", stateClass: "measurement"}, ams.Humidity.Float64())
+		}
+		if features.amsEnvironment && ams.Temp.Present() {
+			h.set(haSensor{key: key + "_temperature", name: name + " temperature", unit: "°C", deviceClass: "temperature", stateClass: "measurement"}, ams.Temp.Float64())
+		}
+
+		for _, tray := range ams.Tray {
+			trayKey, trayName := key+"_tray_"+tray.ID, name+" tray "+tray.ID
+			if value, ok := haText(tray.TrayType); ok {
+				h.set(haSensor{key: trayKey, name: trayName, attributes: trayKey + "_color"}, value)
+			}
+			if value, ok := haText(tray.TrayColor); ok {
+				h.state[trayKey+"_color"] = value
+			}
+			if tray.Remain.Present() && tray.Remain.Float64() >= 0 {
+				h.set(haSensor{key: trayKey + "_remain", name: trayName + " remaining", unit: "%", stateClass: "measurement"}, tray.Remain.Float64())
+			}
+		}
+	}
+
+	h.discoverAll()
+	h.publishState()
+}
+
+// set sets the state of a sensor.
+func (h *homeAssistant) set(s haSensor, value any) {
+	h.state[s.key] = value
+	h.sensors[s.key] = s
+}
+
+// discoverAll publishes the discovery config of every sensor that has not
+// been announced yet.
+func (h *homeAssistant) discoverAll() {
+	for key, s := range h.sensors {
+		if !h.discovered[key] {
+			h.discover(s)
+			h.discovered[key] = true
+		}
+	}
+}
+
+// discover publishes the discovery config of a sensor.
+func (h *homeAssistant) discover(s haSensor) {
+	nodeID := "bambulabs_" + h.serial
+	config := map[string]any{
+		"name":                  s.name,
+		"unique_id":             nodeID + "_" + s.key,
+		"object_id":             nodeID + "_" + s.key,
+		"state_topic":           h.stateTopic(),
+		"value_template":        "{{ value_json." + s.key + " }}",
+		"availability_topic":    h.availabilityTopic(),
+		"payload_available":     "online",
+		"payload_not_available": "offline",
+		"device": map[string]any{
+			"identifiers":   []string{nodeID},
+			"name":          "Bambu Lab " + string(h.model) + " " + h.serial,
+			"manufacturer":  "Bambu Lab",
+			"model":         string(h.model),
+			"serial_number": h.serial,
+		},
+	}
+	if s.unit != "" {
+		config["unit_of_measurement"] = s.unit
+	}
+	if s.deviceClass != "" {
+		config["device_class"] = s.deviceClass
+	}
+	if s.stateClass != "" {
+		config["state_class"] = s.stateClass
+	}
+	if s.attributes != "" {
+		config["json_attributes_topic"] = h.stateTopic()
+		config["json_attributes_template"] = `{{ {"color": value_json.` + s.attributes + `} | tojson }}`
+	}
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		fmt.Printf("Error marshalling discovery config for %s: %s\n", s.key, err)
+		return
+	}
+	h.publish(fmt.Sprintf("%s/sensor/%s/%s/config", h.prefix, nodeID, s.key), payload)
+}
+
+func (h *homeAssistant) publishState() {
+	payload, err := json.Marshal(h.state)
+	if err != nil {
+		fmt.Printf("Error marshalling Home Assistant state: %s\n", err)
+		return
+	}
+	h.publish(h.stateTopic(), payload)
+}
+
+// publish publishes a retained message without waiting for it to be sent,
+// so a slow broker does not hold up the printer connection.
+func (h *homeAssistant) publish(topic string, payload any) {
+	h.client.Publish(topic, 0, true, payload)
+}
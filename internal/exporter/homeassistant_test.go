@@ -0,0 +1,150 @@
+package exporter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// nextState returns the messages published to the broker up to and including
+// the next state document, keyed by topic.
+func nextState(t *testing.T, broker *testBroker) (map[string]*packets.PublishPacket, map[string]any) {
+	t.Helper()
+
+	messages := map[string]*packets.PublishPacket{}
+	for {
+		packet := broker.next(t)
+		messages[packet.TopicName] = packet
+		if packet.TopicName != "bambulabs/00M00A000000000/state" {
+			continue
+		}
+
+		var state map[string]any
+		if err := json.Unmarshal(packet.Payload, &state); err != nil {
+			t.Fatalf("Failed to unmarshal state: %v", err)
+		}
+		return messages, state
+	}
+}
+
+func TestHomeAssistant(t *testing.T) {
+	broker := newTestBroker(t)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_TOPIC":                "device/00M00A000000000/report",
+		"BAMBULABS_HOMEASSISTANT_BROKER": "tcp://" + broker.listener.Addr().String(),
+	})
+	exporter.ConnectToHomeAssistant()
+	t.Cleanup(func() { exporter.homeAssistant.client.Disconnect(0) })
+
+	if online := broker.next(t); online.TopicName != "bambulabs/00M00A000000000/availability" || string(online.Payload) != "online" || !online.Retain {
+		t.Fatalf("Expected retained online availability, got %s %s", online.TopicName, online.Payload)
+	}
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"gcode_state": "RUNNING",
+			"subtask_name": "benchy",
+			"mc_percent": 42,
+			"nozzle_temper": 220.5,
+			"chamber_temper": 35,
+			"ams": {"ams": [{"id": "0", "humidity": "4", "temp": "25.1", "tray": [{"id": "1", "tray_type": "PLA", "tray_color": "FF0000FF", "remain": 80}]}]}
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	messages, state := nextState(t, broker)
+	expected := map[string]any{
+		"state":               "RUNNING",
+		"job":                 "benchy",
+		"progress":            42.0,
+		"nozzle_temperature":  220.5,
+		"chamber_temperature": 35.0,
+		"ams_0_humidity":      4.0,
+		"ams_0_temperature":   25.1,
+		"ams_0_tray_1":        "PLA",
+		"ams_0_tray_1_color":  "FF0000FF",
+		"ams_0_tray_1_remain": 80.0,
+	}
+	for key, value := range expected {
+		if state[key] != value {
+			t.Errorf("Expected state %s = %v, got %v", key, value, state[key])
+		}
+	}
+
+	configTopic := "homeassistant/sensor/bambulabs_00M00A000000000/nozzle_temperature/config"
+	packet, ok := messages[configTopic]
+	if !ok {
+		t.Fatalf("Expected discovery config on %s", configTopic)
+	}
+	var config struct {
+		UniqueID      string `json:"unique_id"`
+		StateTopic    string `json:"state_topic"`
+		ValueTemplate string `json:"value_template"`
+		Unit          string `json:"unit_of_measurement"`
+		DeviceClass   string `json:"device_class"`
+		Device        struct {
+			Identifiers []string `json:"identifiers"`
+			Model       string   `json:"model"`
+		} `json:"device"`
+	}
+	if err := json.Unmarshal(packet.Payload, &config); err != nil {
+		t.Fatalf("Failed to unmarshal discovery config: %v", err)
+	}
+	if !packet.Retain || config.UniqueID != "bambulabs_00M00A000000000_nozzle_temperature" || config.StateTopic != "bambulabs/00M00A000000000/state" ||
+		config.ValueTemplate != "{{ value_json.nozzle_temperature }}" || config.Unit != "°C" || config.DeviceClass != "temperature" || config.Device.Model != "X1C" {
+		t.Errorf("Unexpected discovery config: %+v", config)
+	}
+	if _, ok := messages["homeassistant/sensor/bambulabs_00M00A000000000/ams_0_tray_1/config"]; !ok {
+		t.Error("Expected discovery config for AMS tray 0/1")
+	}
+	for topic := range messages {
+		if strings.HasSuffix(topic, "_color/config") {
+			t.Errorf("Expected tray colors to be attributes, got discovery config %s", topic)
+		}
+	}
+
+	// Partial reports keep the previous state and do not repeat discovery.
+	report = `{"print": {"command": "push_status", "mc_percent": 43, "ams": {"ams": [{"id": "0", "tray": [{"id": "1", "remain": 79}]}]}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	messages, state = nextState(t, broker)
+	if len(messages) != 1 {
+		t.Errorf("Expected only the state to be published, got %d messages", len(messages))
+	}
+	if state["progress"] != 43.0 || state["job"] != "benchy" {
+		t.Errorf("Expected merged state, got %v", state)
+	}
+	if state["ams_0_tray_1"] != "PLA" || state["ams_0_tray_1_color"] != "FF0000FF" || state["ams_0_tray_1_remain"] != 79.0 {
+		t.Errorf("Expected the tray filament to be kept, got %v", state)
+	}
+}
+
+func TestHomeAssistantModelFeatures(t *testing.T) {
+	broker := newTestBroker(t)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_TOPIC":                "device/03900A000000000/report",
+		"BAMBULABS_HOMEASSISTANT_BROKER": "tcp://" + broker.listener.Addr().String(),
+	})
+	exporter.ConnectToHomeAssistant()
+	t.Cleanup(func() { exporter.homeAssistant.client.Disconnect(0) })
+	broker.next(t)
+
+	report := `{"print": {"command": "push_status", "chamber_temper": 5, "ams": {"ams": [{"id": "0", "humidity": "5", "tray": []}]}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	for {
+		packet := broker.next(t)
+		if strings.Contains(packet.TopicName, "chamber_temperature") || strings.Contains(packet.TopicName, "humidity") {
+			t.Errorf("Expected no %s on an A1", packet.TopicName)
+		}
+		if strings.HasSuffix(packet.TopicName, "/state") {
+			if strings.Contains(string(packet.Payload), "chamber") || strings.Contains(string(packet.Payload), "humidity") {
+				t.Errorf("Expected no chamber temperature or AMS humidity in the A1 state, got %s", packet.Payload)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// updateJob replaces the job info series with the job details of the
+// report, so only the current job is exposed. Partial reports without job
+// details leave the series alone.
+func (e *Exporter) updateJob(data BambuLabsX1C) {
+	if data.Print.GcodeFile == "" && data.Print.SubtaskName == "" {
+		return
+	}
+
+	e.jobInfoMetric.Reset()
+	e.jobInfoMetric.With(prometheus.Labels{
+		"file":       data.Print.GcodeFile,
+		"subtask":    data.Print.SubtaskName,
+		"project_id": data.Print.ProjectID,
+		"task_id":    data.Print.TaskID,
+		"print_type": data.Print.PrintType,
+	}).Set(1)
+}
@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestJobInfoMetric(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"gcode_file": "/data/Metadata/plate_1.gcode",
+			"subtask_name": "benchy",
+			"project_id": "12345",
+			"profile_id": "678",
+			"task_id": "901",
+			"subtask_id": "234",
+			"print_type": "cloud"
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	expected := `bambulabs_job_info{file="/data/Metadata/plate_1.gcode",print_type="cloud",project_id="12345",subtask="benchy",task_id="901"} 1`
+	if body := scrape(t, exporter); !strings.Contains(body, expected) {
+		t.Errorf("Expected metrics to contain %q", expected)
+	}
+
+	// Partial reports keep the current job.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status"}}`)})
+	if count := testutil.CollectAndCount(exporter.jobInfoMetric); count != 1 {
+		t.Errorf("Expected the job info to be kept, got %d series", count)
+	}
+
+	report = `{"print": {"command": "push_status", "gcode_file": "cube.gcode", "subtask_name": "cube", "print_type": "local"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if count := testutil.CollectAndCount(exporter.jobInfoMetric); count != 1 {
+		t.Errorf("Expected the previous job to be replaced, got %d series", count)
+	}
+	expected = `bambulabs_job_info{file="cube.gcode",print_type="local",project_id="",subtask="cube",task_id=""} 1`
+	if body := scrape(t, exporter); !strings.Contains(body, expected) {
+		t.Errorf("Expected metrics to contain %q", expected)
+	}
+}
@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"time"
+)
+
+// layerState remembers the last reported layers, as partial reports only
+// include the fields that changed, and the first layer seen for the current
+// job so the layer rate can be estimated.
+type layerState struct {
+	layer      int
+	total      int
+	job        string
+	startLayer int
+	startTime  time.Time
+}
+
+// updateLayers exports the layer progress and two ETAs: the one derived from
+// the printer's remaining time and one estimated from the observed layer rate
+// of the current job. Both ETAs are cleared once the printer is no longer
+// printing.
+func (e *Exporter) updateLayers(data BambuLabsX1C) {
+	now := e.now()
+	if data.Print.LayerNum.Present() {
+		e.layers.layer = data.Print.LayerNum.Int()
+	}
+	if data.Print.TotalLayerNum.Present() {
+		e.layers.total = data.Print.TotalLayerNum.Int()
+	}
+	layer, total := e.layers.layer, e.layers.total
+
+	if total > 0 && (data.Print.LayerNum.Present() || data.Print.TotalLayerNum.Present()) {
+		e.totalLayersMetric.Set(float64(total))
+		e.layerProgressMetric.Set(float64(layer) / float64(total))
+	}
+
+	if state := data.Print.GcodeState; state != "" && state != "RUNNING" && state != "PAUSE" {
+		e.printETAMetric.Set(0)
+		e.estimatedETAMetric.Set(0)
+		return
+	}
+	if data.Print.McRemainingTime.Present() {
+		var eta float64
+		if remaining := data.Print.McRemainingTime.Int(); remaining > 0 {
+			eta = float64(now.Add(time.Duration(remaining) * time.Minute).Unix())
+		}
+		e.printETAMetric.Set(eta)
+	}
+
+	if job := jobKey(data); job != "" && job != e.layers.job {
+		e.layers.job, e.layers.startLayer, e.layers.startTime = job, 0, time.Time{}
+	}
+	if !data.Print.LayerNum.Present() || layer == 0 {
+		return
+	}
+	if e.layers.startTime.IsZero() || layer < e.layers.startLayer {
+		e.layers.startLayer = layer
+		e.layers.startTime = now
+		return
+	}
+
+	printed := layer - e.layers.startLayer
+	elapsed := now.Sub(e.layers.startTime)
+	if printed <= 0 || total <= layer || elapsed <= 0 {
+		return
+	}
+	perLayer := elapsed / time.Duration(printed)
+	eta := now.Add(perLayer * time.Duration(total-layer))
+	e.estimatedETAMetric.Set(float64(eta.Unix()))
+}
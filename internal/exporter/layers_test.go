@@ -0,0 +1,82 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLayerMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	clock := time.Unix(1700000000, 0)
+	exporter.now = func() time.Time { return clock }
+
+	report := func(file string, layer, total, remaining int) {
+		t.Helper()
+		payload := fmt.Sprintf(`{
+			"print": {
+				"command": "push_status",
+				"gcode_file": %q,
+				"gcode_start_time": "1700000000",
+				"layer_num": %d,
+				"total_layer_num": %d,
+				"mc_remaining_time": %d
+			}
+		}`, file, layer, total, remaining)
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(payload)})
+	}
+
+	report("cube.gcode", 10, 100, 90)
+
+	if value := testutil.ToFloat64(exporter.totalLayersMetric); value != 100 {
+		t.Errorf("Expected 100 total layers, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.layerProgressMetric); value != 0.1 {
+		t.Errorf("Expected layer progress 0.1, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.printETAMetric); value != float64(clock.Add(90*time.Minute).Unix()) {
+		t.Errorf("Expected printer ETA in 90 minutes, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.estimatedETAMetric); value != 0 {
+		t.Errorf("Expected no estimated ETA after the first report, got %v", value)
+	}
+
+	// 10 layers in 20 minutes leaves 80 layers, or 160 minutes.
+	clock = clock.Add(20 * time.Minute)
+	report("cube.gcode", 20, 100, 70)
+
+	if value := testutil.ToFloat64(exporter.estimatedETAMetric); value != float64(clock.Add(160*time.Minute).Unix()) {
+		t.Errorf("Expected estimated ETA in 160 minutes, got %v", time.Unix(int64(value), 0).Sub(clock))
+	}
+	if value := testutil.ToFloat64(exporter.printETAMetric); value != float64(clock.Add(70*time.Minute).Unix()) {
+		t.Errorf("Expected printer ETA in 70 minutes, got %v", value)
+	}
+
+	// A new job restarts the estimate.
+	clock = clock.Add(time.Minute)
+	report("benchy.gcode", 1, 50, 30)
+	clock = clock.Add(5 * time.Minute)
+	report("benchy.gcode", 6, 50, 25)
+
+	if value := testutil.ToFloat64(exporter.estimatedETAMetric); value != float64(clock.Add(44*time.Minute).Unix()) {
+		t.Errorf("Expected estimated ETA in 44 minutes, got %v", time.Unix(int64(value), 0).Sub(clock))
+	}
+
+	// Partial reports keep the progress of the last reported layer.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status", "total_layer_num": 50}}`)})
+	if value := testutil.ToFloat64(exporter.layerProgressMetric); value != 0.12 {
+		t.Errorf("Expected layer progress 0.12 after a partial report, got %v", value)
+	}
+
+	// Both ETAs are cleared once the job is over.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status", "gcode_state": "FINISH"}}`)})
+	if value := testutil.ToFloat64(exporter.printETAMetric); value != 0 {
+		t.Errorf("Expected no printer ETA after the job, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.estimatedETAMetric); value != 0 {
+		t.Errorf("Expected no estimated ETA after the job, got %v", value)
+	}
+}
@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// legacyMetric describes how a namespaced metric is re-exposed under the
+// name it had before the bambulabs_ namespace was introduced.
+type legacyMetric struct {
+	name  string
+	help  string
+	scale float64
+
+	// label and value, when set, select the series of a vector that make up
+	// the legacy metric. The label is dropped from the legacy series.
+	label string
+	value string
+}
+
+// legacyFanMetrics splits bambulabs_fan_speed_percent back into the
+// individual gear (0-15) gauges.
+var legacyFanMetrics = []legacyMetric{
+	{name: "big_fan1_speed", help: "Big Fan 1 Speed", scale: 15.0 / 100, label: "fan", value: fanAux},
+	{name: "big_fan2_speed", help: "Big Fan 2 Speed", scale: 15.0 / 100, label: "fan", value: fanChamber},
+	{name: "cooling_fan_speed", help: "Cooling Fan Speed", scale: 15.0 / 100, label: "fan", value: fanPartCooling},
+}
+
+// legacyMetrics maps current metric names to their pre-namespace names.
+// Only metrics that existed before the rename are listed here.
+var legacyMetrics = map[string][]legacyMetric{
+	"bambulabs_ams_humidity_index":                {{name: "ams_humidity", help: "humidity of the ams", scale: 1}},
+	"bambulabs_ams_temperature_celsius":           {{name: "ams_temp", help: "temperature of the ams", scale: 1}},
+	"bambulabs_ams_tray_color_info":               {{name: "ams_tray_color", help: "color of material in ams tray", scale: 1}},
+	"bambulabs_ams_tray_type_info":                {{name: "ams_tray_type", help: "type of material in ams tray", scale: 1}},
+	"bambulabs_layer_number":                      {{name: "layer_number", help: "layer number of the print head in gcode", scale: 1}},
+	"bambulabs_print_error_code":                  {{name: "print_error", help: "Print error int", scale: 1}},
+	"bambulabs_wifi_signal_dbm":                   {{name: "wifi_signal", help: "Wifi signal in dBm", scale: 1}},
+	"bambulabs_chamber_temperature_celsius":       {{name: "chamber_temper", help: "Chamber Temperature of Printer", scale: 1}},
+	"bambulabs_print_fail_reason_code":            {{name: "fail_reason", help: "Print Failure Reason", scale: 1}},
+	"bambulabs_fan_speed_percent":                 legacyFanMetrics,
+	"bambulabs_fan_gear":                          {{name: "fan_gear", help: "Fan Gear", scale: 1}},
+	"bambulabs_print_progress_percent":            {{name: "mc_percent", help: "Percentage of Progress of print", scale: 1}},
+	"bambulabs_mc_print_error_code":               {{name: "mc_print_error_code", help: "Print Progress Error Code", scale: 1}},
+	"bambulabs_mc_print_stage":                    {{name: "mc_print_stage", help: "Print Progress Stage", scale: 1}},
+	"bambulabs_mc_print_sub_stage":                {{name: "mc_print_sub_stage", help: "Print Progress Sub Stage", scale: 1}},
+	"bambulabs_print_remaining_seconds":           {{name: "mc_remaining_time", help: "Print Progress Remaining Time in minutes", scale: 1.0 / 60}},
+	"bambulabs_nozzle_target_temperature_celsius": {{name: "nozzle_target_temper", help: "Nozzle Target Temperature Metric", scale: 1}},
+	"bambulabs_nozzle_temperature_celsius":        {{name: "nozzle_temper", help: "Nozzle Temperature Metric", scale: 1}},
+	"bambulabs_bed_target_temperature_celsius":    {{name: "bed_target_temper", help: "Bed target temperature metric", scale: 1}},
+	"bambulabs_bed_temperature_celsius":           {{name: "bed_temper", help: "Bed temperature metric", scale: 1}},
+}
+
+// legacyGatherer re-exposes the gauges of the wrapped gatherer under their
+// legacy names so dashboards can be migrated gradually. It is only used when
+// BAMBULABS_LEGACY_METRICS is enabled.
+type legacyGatherer struct {
+	gatherer prometheus.Gatherer
+}
+
+func (g legacyGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy []*dto.MetricFamily
+	for _, family := range families {
+		if family.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+		for _, mapping := range legacyMetrics[family.GetName()] {
+			if renamed := mapping.rename(family); len(renamed.Metric) > 0 {
+				legacy = append(legacy, renamed)
+			}
+		}
+	}
+	return legacy, nil
+}
+
+// rename returns the series of family selected by m under the legacy name.
+func (m legacyMetric) rename(family *dto.MetricFamily) *dto.MetricFamily {
+	renamed := &dto.MetricFamily{
+		Name: &m.name,
+		Help: &m.help,
+		Type: family.Type,
+	}
+	for _, metric := range family.Metric {
+		labels := metric.Label
+		if m.label != "" {
+			labels = nil
+			selected := false
+			for _, label := range metric.Label {
+				if label.GetName() != m.label {
+					labels = append(labels, label)
+				} else if label.GetValue() == m.value {
+					selected = true
+				}
+			}
+			if !selected {
+				continue
+			}
+		}
+
+		value := metric.GetGauge().GetValue() * m.scale
+		renamed.Metric = append(renamed.Metric, &dto.Metric{
+			Label: labels,
+			Gauge: &dto.Gauge{Value: &value},
+		})
+	}
+	return renamed
+}
@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+)
+
+const legacyTestMessage = `{
+	"print": {
+		"command": "push_status",
+		"layer_num": 10,
+		"wifi_signal": "-50dBm",
+		"mc_remaining_time": 60,
+		"nozzle_temper": 220.0,
+		"ams": {
+			"ams": [
+				{
+					"id": "0",
+					"humidity": "4",
+					"temp": "25.0",
+					"tray": [{"id": "0", "tray_type": "ABS", "tray_color": "Blue"}]
+				}
+			]
+		}
+	}
+}`
+
+func TestMetricNames(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(legacyTestMessage)})
+
+	body := scrape(t, exporter)
+
+	expected := []string{
+		"bambulabs_layer_number 10",
+		"bambulabs_wifi_signal_dbm -50",
+		"bambulabs_print_remaining_seconds 3600",
+		"bambulabs_nozzle_temperature_celsius 220",
+		`bambulabs_ams_temperature_celsius{ams_number="0"} 25`,
+		`bambulabs_ams_tray_type_info{ams_number="0",tray_number="0",tray_type="ABS"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+
+	if strings.Contains(body, "\nmc_remaining_time ") {
+		t.Errorf("Expected legacy metrics to be disabled by default")
+	}
+}
+
+func TestLegacyMetrics(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_LEGACY_METRICS": "true"})
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(legacyTestMessage)})
+
+	body := scrape(t, exporter)
+
+	expected := []string{
+		"# HELP mc_remaining_time Print Progress Remaining Time in minutes",
+		"\nmc_remaining_time 60\n",
+		"bambulabs_print_remaining_seconds 3600",
+		"\nlayer_number 10\n",
+		"\nwifi_signal -50\n",
+		"\nnozzle_temper 220\n",
+		`ams_temp{ams_number="0"} 25`,
+		`ams_tray_color{ams_number="0",tray_color="Blue",tray_number="0"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+}
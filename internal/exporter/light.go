@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// lightNodes lists the lights that can be controlled with system.ledctrl.
+var lightNodes = map[string]bool{
+	"chamber_light": true,
+	"work_light":    true,
+}
+
+// updateLights exports the state of each light in lights_report. Flashing
+// lights count as on.
+func (e *Exporter) updateLights(data BambuLabsX1C) {
+	for _, light := range data.Print.LightsReport {
+		switch light.Mode {
+		case "on", "flashing":
+			e.lightOnMetric.WithLabelValues(light.Node).Set(1)
+		case "off":
+			e.lightOnMetric.WithLabelValues(light.Node).Set(0)
+		}
+	}
+}
+
+// lightControl switches a light on or off. It is registered when
+// BAMBULABS_LIGHT_CONTROL or BAMBULABS_CONTROL_API is enabled, both of which
+// require BAMBULABS_CONTROL_TOKEN.
+//
+//	POST /control/light?node=chamber_light&mode=on
+func (e *Exporter) lightControl(w http.ResponseWriter, r *http.Request) {
+	e.controlHandler("light", e.publishLight)(w, r)
+}
+
+func (e *Exporter) publishLight(r *http.Request) (string, error) {
+	node := r.FormValue("node")
+	if node == "" {
+		node = "chamber_light"
+	}
+	mode := r.FormValue("mode")
+	params := fmt.Sprintf("node=%s mode=%s", node, mode)
+
+	if !lightNodes[node] {
+		return params, invalidRequestError(fmt.Sprintf("unknown light %q", node))
+	}
+	if mode != "on" && mode != "off" {
+		return params, invalidRequestError("mode must be on or off")
+	}
+	return params, e.setLight(e.client, node, mode)
+}
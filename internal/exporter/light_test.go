@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLightMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"lights_report": [
+				{"node": "chamber_light", "mode": "on"},
+				{"node": "work_light", "mode": "flashing"}
+			]
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.lightOnMetric.WithLabelValues("chamber_light")); value != 1 {
+		t.Errorf("Expected chamber light on, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.lightOnMetric.WithLabelValues("work_light")); value != 1 {
+		t.Errorf("Expected flashing work light on, got %v", value)
+	}
+
+	report = `{"print": {"command": "push_status", "lights_report": [{"node": "chamber_light", "mode": "off"}]}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.lightOnMetric.WithLabelValues("chamber_light")); value != 0 {
+		t.Errorf("Expected chamber light off, got %v", value)
+	}
+}
+
+func TestLightControl(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_LIGHT_CONTROL": "true",
+		"BAMBULABS_CONTROL_TOKEN": "secret",
+	})
+	client := &mockClient{}
+	exporter.client = client
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		expectedStatus int
+	}{
+		{"switch on", http.MethodPost, "/control/light?mode=on", http.StatusOK},
+		{"switch off", http.MethodPost, "/control/light?node=chamber_light&mode=off", http.StatusOK},
+		{"wrong method", http.MethodGet, "/control/light?mode=on", http.StatusMethodNotAllowed},
+		{"unknown node", http.MethodPost, "/control/light?node=laser&mode=on", http.StatusBadRequest},
+		{"invalid mode", http.MethodPost, "/control/light?mode=disco", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rr := httptest.NewRecorder()
+			exporter.lightControl(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+
+	if len(client.published) != 2 {
+		t.Fatalf("Expected 2 published commands, got %d", len(client.published))
+	}
+
+	var request ledControlRequest
+	if err := json.Unmarshal(client.published[1].payload, &request); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+	if client.published[1].topic != "device/test123/request" {
+		t.Errorf("Expected topic 'device/test123/request', got '%s'", client.published[1].topic)
+	}
+	if request.System.Command != "ledctrl" || request.System.LedNode != "chamber_light" || request.System.LedMode != "off" {
+		t.Errorf("Unexpected ledctrl request: %+v", request.System)
+	}
+}
@@ -0,0 +1,156 @@
+package exporter
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrinterModel identifies a Bambu Lab printer model.
+type PrinterModel string
+
+const (
+	ModelUnknown PrinterModel = "unknown"
+	ModelX1      PrinterModel = "X1"
+	ModelX1C     PrinterModel = "X1C"
+	ModelX1E     PrinterModel = "X1E"
+	ModelP1P     PrinterModel = "P1P"
+	ModelP1S     PrinterModel = "P1S"
+	ModelA1      PrinterModel = "A1"
+	ModelA1Mini  PrinterModel = "A1mini"
+)
+
+// modelFeatures lists the optional hardware a printer model reports on.
+// Metrics for hardware a model does not have are not exposed, so they do not
+// show up as zero values.
+type modelFeatures struct {
+	// chamberTemperature is true when the printer has a chamber temperature
+	// sensor.
+	chamberTemperature bool
+	// amsEnvironment is true when the attached AMS reports humidity and
+	// temperature. The AMS Lite used by the A1 series does not.
+	amsEnvironment bool
+	// auxFan is true when the printer has an auxiliary part cooling fan.
+	auxFan bool
+	// chamberFan is true when the printer has a chamber exhaust fan.
+	chamberFan bool
+}
+
+var modelFeatureSet = map[PrinterModel]modelFeatures{
+	ModelX1:     {chamberTemperature: true, amsEnvironment: true, auxFan: true, chamberFan: true},
+	ModelX1C:    {chamberTemperature: true, amsEnvironment: true, auxFan: true, chamberFan: true},
+	ModelX1E:    {chamberTemperature: true, amsEnvironment: true, auxFan: true, chamberFan: true},
+	ModelP1P:    {amsEnvironment: true, auxFan: true},
+	ModelP1S:    {amsEnvironment: true, auxFan: true, chamberFan: true},
+	ModelA1:     {},
+	ModelA1Mini: {},
+}
+
+// serialPrefixes maps the first characters of a printer serial number to its
+// model.
+var serialPrefixes = map[string]PrinterModel{
+	"00M": ModelX1C,
+	"03W": ModelX1E,
+	"01S": ModelP1P,
+	"01P": ModelP1S,
+	"039": ModelA1,
+	"030": ModelA1Mini,
+}
+
+// projectNames maps the project_name of the ota module in a get_version
+// reply to its model.
+var projectNames = map[string]PrinterModel{
+	"BL-P001": ModelX1C,
+	"BL-P002": ModelX1,
+	"C13":     ModelX1E,
+	"C11":     ModelP1P,
+	"C12":     ModelP1S,
+	"N2S":     ModelA1,
+	"N1":      ModelA1Mini,
+}
+
+// ParsePrinterModel returns the model with the given name, ignoring case and
+// spaces. Unknown names return ModelUnknown.
+func ParsePrinterModel(name string) PrinterModel {
+	name = strings.ReplaceAll(name, " ", "")
+	for model := range modelFeatureSet {
+		if strings.EqualFold(string(model), name) {
+			return model
+		}
+	}
+	return ModelUnknown
+}
+
+// modelFromSerial detects the model from the prefix of a serial number.
+func modelFromSerial(serial string) PrinterModel {
+	if len(serial) < 3 {
+		return ModelUnknown
+	}
+	if model, ok := serialPrefixes[serial[:3]]; ok {
+		return model
+	}
+	return ModelUnknown
+}
+
+// modelFromProjectName detects the model from a get_version project name.
+func modelFromProjectName(name string) PrinterModel {
+	if model, ok := projectNames[name]; ok {
+		return model
+	}
+	return ModelUnknown
+}
+
+// serial returns the printer serial number taken from the report topic,
+// e.g. device/<serial>/report.
+func (e *Exporter) serial() string {
+	parts := strings.Split(e.config.Topic, "/")
+	if len(parts) < 2 || parts[0] != "device" {
+		return ""
+	}
+	return parts[1]
+}
+
+// detectModel returns the configured model, falling back to the model
+// derived from the serial number.
+func (e *Exporter) detectModel() PrinterModel {
+	if e.config.Model != "" {
+		return ParsePrinterModel(e.config.Model)
+	}
+	return modelFromSerial(e.serial())
+}
+
+// setModel records the printer model and stops exposing metrics for hardware
+// the model does not have. An unknown model exposes everything.
+func (e *Exporter) setModel(model PrinterModel) {
+	e.model = model
+
+	e.printerInfoMetric.Reset()
+	e.printerInfoMetric.With(prometheus.Labels{"model": string(model), "serial": e.serial()}).Set(1)
+
+	features, ok := modelFeatureSet[model]
+	if !ok {
+		return
+	}
+	if !features.chamberTemperature {
+		e.registry.Unregister(e.chamberTemperMetric)
+	}
+	if !features.amsEnvironment {
+		e.registry.Unregister(e.amsHumidityMetric)
+		e.registry.Unregister(e.amsTempMetric)
+	}
+	if !features.auxFan {
+		e.fanSpeedMetric.DeleteLabelValues(fanAux)
+	}
+	if !features.chamberFan {
+		e.fanSpeedMetric.DeleteLabelValues(fanChamber)
+	}
+}
+
+// features returns the hardware features of the detected model. Unknown
+// models are assumed to have every feature.
+func (e *Exporter) features() modelFeatures {
+	if features, ok := modelFeatureSet[e.model]; ok {
+		return features
+	}
+	return modelFeatures{chamberTemperature: true, amsEnvironment: true, auxFan: true, chamberFan: true}
+}
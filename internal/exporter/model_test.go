@@ -0,0 +1,138 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelFromSerial(t *testing.T) {
+	tests := []struct {
+		serial   string
+		expected PrinterModel
+	}{
+		{"00M09A350100123", ModelX1C},
+		{"03W00X000000000", ModelX1E},
+		{"01S00C000000000", ModelP1P},
+		{"01P00A000000000", ModelP1S},
+		{"03919A000000000", ModelA1},
+		{"0309DA000000000", ModelA1Mini},
+		{"test123", ModelUnknown},
+		{"", ModelUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.serial, func(t *testing.T) {
+			if model := modelFromSerial(tt.serial); model != tt.expected {
+				t.Errorf("Expected model %s, got %s", tt.expected, model)
+			}
+		})
+	}
+}
+
+func TestParsePrinterModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected PrinterModel
+	}{
+		{"X1C", ModelX1C},
+		{"x1c", ModelX1C},
+		{"P1S", ModelP1S},
+		{"A1 mini", ModelA1Mini},
+		{"A1mini", ModelA1Mini},
+		{"Ender 3", ModelUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if model := ParsePrinterModel(tt.name); model != tt.expected {
+				t.Errorf("Expected model %s, got %s", tt.expected, model)
+			}
+		})
+	}
+}
+
+func TestModelFeatureMetrics(t *testing.T) {
+	report := `{
+		"print": {
+			"command": "push_status",
+			"chamber_temper": 5,
+			"big_fan1_speed": "0",
+			"big_fan2_speed": "0",
+			"cooling_fan_speed": "15",
+			"ams": {"ams": [{"id": "0", "humidity": "5", "temp": "0.0", "tray": [{"id": "0", "tray_type": "PLA", "tray_color": "FFFFFFFF"}]}]}
+		}
+	}`
+
+	tests := []struct {
+		name       string
+		topic      string
+		model      string
+		info       string
+		present    []string
+		notPresent []string
+	}{
+		{
+			name:    "X1C from serial",
+			topic:   "device/00M09A350100123/report",
+			info:    `bambulabs_printer_info{model="X1C",serial="00M09A350100123"} 1`,
+			present: []string{"bambulabs_chamber_temperature_celsius", "bambulabs_ams_humidity_index", `bambulabs_fan_speed_percent{fan="aux"}`, `bambulabs_fan_speed_percent{fan="chamber"}`},
+		},
+		{
+			name:       "P1P from serial",
+			topic:      "device/01S00C000000000/report",
+			info:       `bambulabs_printer_info{model="P1P",serial="01S00C000000000"} 1`,
+			present:    []string{"bambulabs_ams_humidity_index", `bambulabs_fan_speed_percent{fan="aux"}`},
+			notPresent: []string{"bambulabs_chamber_temperature_celsius", `bambulabs_fan_speed_percent{fan="chamber"}`},
+		},
+		{
+			name:       "A1 mini from config",
+			topic:      "device/test123/report",
+			model:      "A1 mini",
+			info:       `bambulabs_printer_info{model="A1mini",serial="test123"} 1`,
+			present:    []string{"bambulabs_ams_tray_type_info", `bambulabs_fan_speed_percent{fan="part_cooling"}`},
+			notPresent: []string{"bambulabs_chamber_temperature_celsius", "bambulabs_ams_humidity_index", "bambulabs_ams_temperature_celsius", `bambulabs_fan_speed_percent{fan="aux"}`, `bambulabs_fan_speed_percent{fan="chamber"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := newTestExporter(t, map[string]string{
+				"BAMBULABS_TOPIC": tt.topic,
+				"BAMBULABS_MODEL": tt.model,
+			})
+			exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+			body := scrape(t, exporter)
+			if !strings.Contains(body, tt.info) {
+				t.Errorf("Expected metrics to contain %q", tt.info)
+			}
+			for _, name := range tt.present {
+				if !strings.Contains(body, name) {
+					t.Errorf("Expected metric %s to be exposed", name)
+				}
+			}
+			for _, name := range tt.notPresent {
+				if strings.Contains(body, name) {
+					t.Errorf("Expected metric %s not to be exposed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestModelFromVersionInfo(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	if exporter.model != ModelUnknown {
+		t.Fatalf("Expected unknown model, got %s", exporter.model)
+	}
+
+	reply := `{"info": {"command": "get_version", "module": [{"name": "ota", "project_name": "C12", "sw_ver": "01.06.00.00"}], "result": "success"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+
+	if exporter.model != ModelP1S {
+		t.Errorf("Expected model P1S, got %s", exporter.model)
+	}
+	if body := scrape(t, exporter); strings.Contains(body, "# TYPE bambulabs_chamber_temperature_celsius ") {
+		t.Errorf("Expected chamber temperature not to be exposed for P1S")
+	}
+}
@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// notifyTimeout bounds a single delivery attempt.
+const notifyTimeout = 10 * time.Second
+
+// notifier delivers events to an external service.
+type notifier interface {
+	// name identifies the notifier in metrics and logs.
+	name() string
+	// accepts reports whether the notifier wants events of the given type.
+	accepts(eventType string) bool
+	notify(ctx context.Context, event Event) error
+}
+
+// eventFilter limits a notifier to a list of event types. An empty filter
+// accepts every event.
+type eventFilter []string
+
+func (f eventFilter) accepts(eventType string) bool {
+	return len(f) == 0 || slices.Contains(f, eventType)
+}
+
+// permanentError marks a delivery error that retrying will not fix, e.g. a
+// rejected request.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// notifyQueueSize is the number of events buffered per notifier before new
+// events are dropped.
+const notifyQueueSize = 100
+
+// emit counts an event and queues it for every notifier that accepts it.
+// Each notifier delivers its events in order in the background, so a slow
+// service does not hold up the MQTT client.
+func (e *Exporter) emit(event Event) {
+	fmt.Printf("Event %s: %s\n", event.Type, event.Message)
+	e.eventsMetric.WithLabelValues(event.Type).Inc()
+
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	if e.notifyStopped {
+		return
+	}
+	for _, n := range e.notifiers {
+		if !n.accepts(event.Type) {
+			continue
+		}
+
+		queue, ok := e.queues[n]
+		if !ok {
+			queue = make(chan Event, notifyQueueSize)
+			if e.queues == nil {
+				e.queues = map[notifier]chan Event{}
+			}
+			e.queues[n] = queue
+			go func() {
+				for event := range queue {
+					e.deliver(n, event)
+					e.deliveries.Done()
+				}
+			}()
+		}
+
+		e.deliveries.Add(1)
+		select {
+		case queue <- event:
+		default:
+			e.deliveries.Done()
+			fmt.Printf("Dropping %s event for %s, too many queued events\n", event.Type, n.name())
+			e.notificationsMetric.WithLabelValues(n.name(), event.Type, "dropped").Inc()
+		}
+	}
+}
+
+// notifyStopTimeout bounds how long StopNotifiers waits for queued events.
+const notifyStopTimeout = 30 * time.Second
+
+// StopNotifiers stops queueing events and waits for the queued ones to be
+// delivered, so e.g. a job_finished webhook is not lost on exit.
+func (e *Exporter) StopNotifiers() {
+	if !e.stopNotifiers(notifyStopTimeout) {
+		fmt.Printf("Giving up on queued notifications after %s\n", notifyStopTimeout)
+	}
+}
+
+// stopNotifiers reports whether the queued events were delivered before the
+// timeout.
+func (e *Exporter) stopNotifiers(timeout time.Duration) bool {
+	e.notifyMu.Lock()
+	e.notifyStopped = true
+	e.notifyMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.deliveries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// deliver sends an event to a notifier, retrying failed attempts with an
+// exponential backoff starting at BAMBULABS_NOTIFY_BACKOFF.
+func (e *Exporter) deliver(n notifier, event Event) {
+	backoff := e.config.NotifyBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err := n.notify(ctx, event)
+		cancel()
+		if err == nil {
+			e.notificationsMetric.WithLabelValues(n.name(), event.Type, "sent").Inc()
+			return
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= e.config.NotifyRetries {
+			fmt.Printf("Error sending %s event to %s: %s\n", event.Type, n.name(), err)
+			e.notificationsMetric.WithLabelValues(n.name(), event.Type, "failed").Inc()
+			return
+		}
+		e.notificationsMetric.WithLabelValues(n.name(), event.Type, "retried").Inc()
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
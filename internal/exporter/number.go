@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Number is a numeric field of a printer report. Firmware versions disagree
+// on whether a field is sent as a JSON number or as a string, so both are
+// accepted. Values that are neither are recorded as invalid instead of
+// failing the whole report, and fields missing from partial reports can be
+// told apart from zero with Present.
+type Number struct {
+	value   float64
+	present bool
+	invalid bool
+}
+
+// NewNumber returns a present Number with the given value.
+func NewNumber(value float64) Number {
+	return Number{value: value, present: true}
+}
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	*n = Number{}
+
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	raw := string(data)
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			n.invalid = true
+			return nil
+		}
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return nil
+		}
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		n.invalid = true
+		return nil
+	}
+	*n = NewNumber(value)
+	return nil
+}
+
+func (n Number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.value)
+}
+
+// Float64 returns the value, or 0 when it is missing or invalid.
+func (n Number) Float64() float64 {
+	return n.value
+}
+
+// Int returns the value truncated to an int, or 0 when it is missing or
+// invalid.
+func (n Number) Int() int {
+	return int(n.value)
+}
+
+// Present reports whether the field was included in the report with a valid
+// value.
+func (n Number) Present() bool {
+	return n.present
+}
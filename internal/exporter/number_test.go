@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNumberUnmarshal(t *testing.T) {
+	tests := []struct {
+		input   string
+		value   float64
+		present bool
+		invalid bool
+	}{
+		{`5`, 5, true, false},
+		{`25.5`, 25.5, true, false},
+		{`"45.2"`, 45.2, true, false},
+		{`" 7 "`, 7, true, false},
+		{`"-3"`, -3, true, false},
+		{`""`, 0, false, false},
+		{`null`, 0, false, false},
+		{`"fast"`, 0, false, true},
+		{`true`, 0, false, true},
+		{`{}`, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		var n Number
+		if err := n.UnmarshalJSON([]byte(tt.input)); err != nil {
+			t.Errorf("UnmarshalJSON(%s) returned error: %v", tt.input, err)
+		}
+		if n.Float64() != tt.value || n.Present() != tt.present || n.invalid != tt.invalid {
+			t.Errorf("UnmarshalJSON(%s) = %v, present %v, invalid %v, expected %v, %v, %v",
+				tt.input, n.Float64(), n.Present(), n.invalid, tt.value, tt.present, tt.invalid)
+		}
+	}
+}
+
+func TestNumberMarshal(t *testing.T) {
+	payload, err := json.Marshal(NewNumber(42.5))
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(payload) != "42.5" {
+		t.Errorf("Expected 42.5, got %s", payload)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"layer_num": "12",
+			"nozzle_temper": "hot",
+			"gcode_state": 5,
+			"subtask_name": true,
+			"ams": {"ams": [{"id": "0", "humidity": "dry", "tray": [{"id": "0", "remain": "full", "tray_type": 1}, {"id": "1", "remain": "empty", "tray_type": 2}]}]}
+		},
+		"info": {"command": "get_version", "module": [{"name": 7, "sw_ver": "01.08.02.00"}]}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.layerNumberMetric); value != 12 {
+		t.Errorf("Expected layer number 12, got %v", value)
+	}
+	// Every mistyped field is counted once, even though the message has two
+	// sections decoded into the same struct.
+	for _, field := range []string{"print.gcode_state", "print.subtask_name", "print.nozzle_temper", "print.ams.ams.humidity", "print.ams.ams.tray.remain", "print.ams.ams.tray.tray_type", "info.module.name"} {
+		if value := testutil.ToFloat64(exporter.decodeErrorsMetric.WithLabelValues(field)); value != 1 {
+			t.Errorf("Expected 1 decode error for %s, got %v", field, value)
+		}
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_STRICT_DECODING": "true"})
+
+	report := `{"print": {"command": "push_status", "layer_num": 3, "nozzle_temper": "hot"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	if value := testutil.ToFloat64(exporter.layerNumberMetric); value != 0 {
+		t.Errorf("Expected the report to be dropped, got layer number %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.decodeErrorsMetric.WithLabelValues("print.nozzle_temper")); value != 1 {
+		t.Errorf("Expected 1 decode error, got %v", value)
+	}
+}
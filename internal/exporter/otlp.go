@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// otlpShutdownTimeout bounds the last push on exit.
+const otlpShutdownTimeout = 10 * time.Second
+
+// StartOTLP periodically pushes the metrics in the registry to the OTLP
+// collector in BAMBULABS_OTLP_ENDPOINT. It does nothing when it is not set.
+// The /metrics endpoint keeps working alongside it.
+func (e *Exporter) StartOTLP() {
+	if e.config.OtlpEndpoint == "" {
+		return
+	}
+
+	ctx := context.Background()
+	exporter, err := e.newOTLPExporter(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	// The printer is the resource. OTEL_RESOURCE_ATTRIBUTES can add more
+	// attributes, e.g. the site it is in. A model only learned from the
+	// get_version reply after the resource is created is left out, it is
+	// still a label of bambulabs_printer_info.
+	attributes := []attribute.KeyValue{
+		attribute.String("service.name", "bambulabs-exporter"),
+		attribute.String("printer.serial", e.serial()),
+	}
+	if e.model != ModelUnknown {
+		attributes = append(attributes, attribute.String("printer.model", string(e.model)))
+	}
+	res, err := resource.New(ctx, resource.WithFromEnv(), resource.WithAttributes(attributes...))
+	if err != nil {
+		panic(fmt.Errorf("creating OTLP resource: %w", err))
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(e.config.OtlpInterval),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(e.registry))),
+	)
+	e.meterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+	fmt.Printf("Pushing metrics to %s every %s\n", e.config.OtlpEndpoint, e.config.OtlpInterval)
+}
+
+// StopOTLP pushes the metrics one last time and stops pushing, so the last
+// interval is not lost on exit. It does nothing when OTLP is not enabled.
+func (e *Exporter) StopOTLP() {
+	if e.meterProvider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), otlpShutdownTimeout)
+	defer cancel()
+	if err := e.meterProvider.Shutdown(ctx); err != nil {
+		fmt.Printf("Error pushing the last metrics to %s: %s\n", e.config.OtlpEndpoint, err)
+	}
+}
+
+// newOTLPExporter returns an OTLP exporter for BAMBULABS_OTLP_PROTOCOL. The
+// endpoint is a URL, its scheme selects whether TLS is used.
+func (e *Exporter) newOTLPExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	endpoint, err := url.Parse(e.config.OtlpEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing BAMBULABS_OTLP_ENDPOINT: %w", err)
+	}
+
+	switch e.config.OtlpProtocol {
+	case "grpc":
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(endpoint.String()),
+			otlpmetricgrpc.WithHeaders(e.config.OtlpHeaders),
+		)
+	case "http":
+		if endpoint.Path == "" || endpoint.Path == "/" {
+			endpoint.Path = "/v1/metrics"
+		}
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(endpoint.String()),
+			otlpmetrichttp.WithHeaders(e.config.OtlpHeaders),
+		)
+	default:
+		return nil, fmt.Errorf("unknown BAMBULABS_OTLP_PROTOCOL %q, expected grpc or http", e.config.OtlpProtocol)
+	}
+}
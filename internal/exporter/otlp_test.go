@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver is an OTLP gRPC metrics service that records every export.
+type otlpReceiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	requests chan *colmetricspb.ExportMetricsServiceRequest
+}
+
+func (r *otlpReceiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	r.requests <- req
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// pushOTLP exports a report to the receiver and returns the pushed metrics.
+func pushOTLP(t *testing.T, exporter *Exporter, requests chan *colmetricspb.ExportMetricsServiceRequest) *colmetricspb.ExportMetricsServiceRequest {
+	t.Helper()
+
+	report := `{"print": {"command": "push_status", "layer_num": 12, "nozzle_temper": 215.5}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	exporter.StartOTLP()
+	// Unless detected from the serial, the model is only learned from the
+	// get_version reply after OTLP has started.
+	exporter.setModel(ModelX1C)
+	exporter.StopOTLP()
+	return <-requests
+}
+
+// checkOTLP checks the resource and the values of a few pushed metrics.
+// resourceModel is the expected printer.model attribute, empty when the model
+// was not known when OTLP started.
+func checkOTLP(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest, serial, resourceModel string) {
+	t.Helper()
+
+	if len(req.ResourceMetrics) != 1 {
+		t.Fatalf("Expected 1 resource, got %d", len(req.ResourceMetrics))
+	}
+	resourceMetrics := req.ResourceMetrics[0]
+
+	attributes := map[string]string{}
+	for _, attribute := range resourceMetrics.Resource.Attributes {
+		attributes[attribute.Key] = attribute.Value.GetStringValue()
+	}
+	if attributes["printer.serial"] != serial || attributes["printer.model"] != resourceModel || attributes["service.name"] != "bambulabs-exporter" {
+		t.Errorf("Unexpected resource attributes: %v", attributes)
+	}
+
+	values := map[string]float64{}
+	var model string
+	for _, scope := range resourceMetrics.ScopeMetrics {
+		for _, metric := range scope.Metrics {
+			if gauge := metric.GetGauge(); gauge != nil && len(gauge.DataPoints) == 1 {
+				values[metric.Name] = gauge.DataPoints[0].GetAsDouble()
+				if metric.Name == "bambulabs_printer_info" {
+					for _, attribute := range gauge.DataPoints[0].Attributes {
+						if attribute.Key == "model" {
+							model = attribute.Value.GetStringValue()
+						}
+					}
+				}
+			}
+		}
+	}
+	if model != "X1C" {
+		t.Errorf("Expected the model learned after starting in bambulabs_printer_info, got %q", model)
+	}
+	if values["bambulabs_layer_number"] != 12 || values["bambulabs_nozzle_temperature_celsius"] != 215.5 {
+		t.Errorf("Unexpected metric values: layer %v, nozzle %v", values["bambulabs_layer_number"], values["bambulabs_nozzle_temperature_celsius"])
+	}
+}
+
+func TestOTLPGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	receiver := &otlpReceiver{requests: make(chan *colmetricspb.ExportMetricsServiceRequest, 1)}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, receiver)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_TOPIC":         "device/00M00A000000000/report",
+		"BAMBULABS_OTLP_ENDPOINT": "http://" + listener.Addr().String(),
+	})
+	checkOTLP(t, pushOTLP(t, exporter, receiver.requests), "00M00A000000000", "X1C")
+}
+
+func TestOTLPHTTP(t *testing.T) {
+	requests := make(chan *colmetricspb.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Unexpected request to %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := &colmetricspb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("Failed to unmarshal export: %v", err)
+		}
+		requests <- req
+
+		response, _ := proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_OTLP_ENDPOINT": server.URL,
+		"BAMBULABS_OTLP_PROTOCOL": "http",
+		"BAMBULABS_OTLP_HEADERS":  "Authorization:Bearer secret",
+	})
+	checkOTLP(t, pushOTLP(t, exporter, requests), "test123", "")
+}
+
+func TestOTLPDisabled(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	exporter.StartOTLP()
+	if exporter.meterProvider != nil {
+		t.Error("Expected no OTLP export by default")
+	}
+}
@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// proxy is an MQTT broker that re-serves the printer's report topic and
+// forwards publishes on its request topic to the printer, so other tools can
+// share the exporter's connection instead of opening their own. Printers
+// only accept a few LAN clients.
+type proxy struct {
+	mochi.HookBase
+	exporter *Exporter
+	server   *mochi.Server
+}
+
+// StartProxy starts the proxy on BAMBULABS_PROXY_ADDRESS. It does nothing
+// when it is not set. It must be called after ConnectToBroker, as requests
+// are forwarded over the printer connection.
+func (e *Exporter) StartProxy() {
+	if e.config.ProxyAddress == "" {
+		return
+	}
+
+	tlsConfig, err := e.proxyTLSConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	p := &proxy{
+		exporter: e,
+		server: mochi.New(&mochi.Options{
+			InlineClient: true,
+			Logger:       slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		}),
+	}
+	if err := p.server.AddHook(p, nil); err != nil {
+		panic(err)
+	}
+	listener := listeners.NewTCP(listeners.Config{ID: "proxy", Address: e.config.ProxyAddress, TLSConfig: tlsConfig})
+	if err := p.server.AddListener(listener); err != nil {
+		panic(err)
+	}
+	if err := p.server.Serve(); err != nil {
+		panic(err)
+	}
+
+	e.proxy.Store(p)
+	fmt.Printf("Proxying %s on mqtts://%s\n", e.config.Topic, listener.Address())
+}
+
+// proxyTLSConfig loads the certificate in BAMBULABS_PROXY_TLS_CERT and
+// BAMBULABS_PROXY_TLS_KEY, or generates a self-signed one. Clients connect
+// to printers without verifying the certificate, so the self-signed
+// certificate works for most of them.
+func (e *Exporter) proxyTLSConfig() (*tls.Config, error) {
+	if e.config.ProxyTLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(e.config.ProxyTLSCert, e.config.ProxyTLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading proxy certificate: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+	}
+
+	cert, err := selfSignedCertificate(e.serial())
+	if err != nil {
+		return nil, fmt.Errorf("generating proxy certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// selfSignedCertificate generates a certificate for commonName, like the
+// ones printers use.
+func selfSignedCertificate(commonName string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// report re-serves a message received from the printer.
+func (p *proxy) report(payload []byte) {
+	if err := p.server.Publish(p.exporter.config.Topic, payload, false, 0); err != nil {
+		fmt.Printf("Error proxying report: %s\n", err)
+		return
+	}
+	p.exporter.proxyMessagesMetric.WithLabelValues("report").Inc()
+}
+
+func (p *proxy) ID() string {
+	return "bambulabs-proxy"
+}
+
+func (p *proxy) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mochi.OnConnectAuthenticate,
+		mochi.OnACLCheck,
+		mochi.OnSessionEstablished,
+		mochi.OnDisconnect,
+		mochi.OnPublish,
+	}, []byte{b})
+}
+
+// OnConnectAuthenticate accepts the printer's credentials, so clients that
+// talked to the printer directly only need a new address.
+func (p *proxy) OnConnectAuthenticate(cl *mochi.Client, pk packets.Packet) bool {
+	cfg := p.exporter.config
+	return subtle.ConstantTimeCompare(pk.Connect.Username, []byte(cfg.Username)) == 1 &&
+		subtle.ConstantTimeCompare(pk.Connect.Password, []byte(cfg.Password)) == 1
+}
+
+// OnACLCheck allows subscribing to the report topic and publishing to the
+// request topic only.
+func (p *proxy) OnACLCheck(cl *mochi.Client, topic string, write bool) bool {
+	if write {
+		return topic == p.exporter.requestTopic()
+	}
+	return topic == p.exporter.config.Topic
+}
+
+func (p *proxy) OnSessionEstablished(cl *mochi.Client, pk packets.Packet) {
+	fmt.Printf("Proxy client %s connected from %s\n", cl.ID, cl.Net.Remote)
+	p.exporter.proxyClientsMetric.Inc()
+}
+
+func (p *proxy) OnDisconnect(cl *mochi.Client, err error, expire bool) {
+	fmt.Printf("Proxy client %s disconnected: %v\n", cl.ID, err)
+	p.exporter.proxyClientsMetric.Dec()
+}
+
+// OnPublish forwards a request to the printer.
+func (p *proxy) OnPublish(cl *mochi.Client, pk packets.Packet) (packets.Packet, error) {
+	if cl.Net.Inline {
+		return pk, nil
+	}
+
+	p.exporter.client.Publish(p.exporter.requestTopic(), 0, false, pk.Payload)
+	p.exporter.proxyMessagesMetric.WithLabelValues("request").Inc()
+	return pk, nil
+}
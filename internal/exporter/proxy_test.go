@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// connectToProxy returns a client connected to the proxy with the given
+// credentials, or the connection error.
+func connectToProxy(t *testing.T, exporter *Exporter, username, password string) (mqtt.Client, error) {
+	t.Helper()
+
+	listener, _ := exporter.proxy.Load().server.Listeners.Get("proxy")
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker("ssl://" + listener.Address())
+	opts.SetClientID("proxy-test-" + username)
+	opts.SetUsername(username)
+	opts.SetPassword(password)
+	opts.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	token.Wait()
+	if token.Error() != nil {
+		return nil, token.Error()
+	}
+	t.Cleanup(func() { client.Disconnect(0) })
+	return client, nil
+}
+
+func TestProxy(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_PROXY_ADDRESS": "127.0.0.1:0"})
+	broker := newTestBroker(t)
+	exporter.client = broker.connect(t)
+	exporter.StartProxy()
+	t.Cleanup(func() { exporter.proxy.Load().server.Close() })
+
+	if _, err := connectToProxy(t, exporter, "testuser", "wrong"); err == nil {
+		t.Error("Expected the proxy to reject a wrong password")
+	}
+
+	client, err := connectToProxy(t, exporter, "testuser", "testpass")
+	if err != nil {
+		t.Fatalf("Failed to connect to the proxy: %v", err)
+	}
+	reports := make(chan string, 1)
+	if token := client.Subscribe("device/test123/report", 0, func(_ mqtt.Client, msg mqtt.Message) {
+		reports <- string(msg.Payload())
+	}); token.Wait() && token.Error() != nil {
+		t.Fatalf("Failed to subscribe: %v", token.Error())
+	}
+
+	report := `{"print": {"command": "push_status", "layer_num": 7}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+	select {
+	case payload := <-reports:
+		if payload != report {
+			t.Errorf("Expected the raw report, got %s", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the proxied report")
+	}
+	if value := testutil.ToFloat64(exporter.layerNumberMetric); value != 7 {
+		t.Errorf("Expected the report to be exported too, got layer %v", value)
+	}
+
+	// Only the request topic is forwarded to the printer.
+	client.Publish("device/other/request", 0, false, `{"print": {"command": "stop"}}`).Wait()
+	request := `{"print": {"sequence_id": "1", "command": "pause"}}`
+	client.Publish("device/test123/request", 0, false, request).Wait()
+
+	packet := broker.next(t)
+	if packet.TopicName != "device/test123/request" || string(packet.Payload) != request {
+		t.Errorf("Expected the request to be forwarded, got %s %s", packet.TopicName, packet.Payload)
+	}
+
+	if value := testutil.ToFloat64(exporter.proxyClientsMetric); value != 1 {
+		t.Errorf("Expected 1 proxy client, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.proxyMessagesMetric.WithLabelValues("report")); value != 1 {
+		t.Errorf("Expected 1 proxied report, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.proxyMessagesMetric.WithLabelValues("request")); value != 1 {
+		t.Errorf("Expected 1 forwarded request, got %v", value)
+	}
+}
+
+func TestProxyDisabled(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	exporter.StartProxy()
+	if exporter.proxy.Load() != nil {
+		t.Error("Expected no proxy by default")
+	}
+}
@@ -0,0 +1,236 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// sample is the value of a metric with its sorted labels.
+type sample struct {
+	name   string
+	labels []*dto.LabelPair
+	value  float64
+}
+
+// pushBatch is a snapshot of all metrics taken at one point in time.
+type pushBatch struct {
+	time    time.Time
+	samples []sample
+}
+
+// pushFormat encodes batches for a type of endpoint.
+type pushFormat struct {
+	contentType string
+	headers     map[string]string
+	encode      func(pushBatch) []byte
+}
+
+var pushFormats = map[string]pushFormat{
+	"remote_write": {
+		contentType: "application/x-protobuf",
+		headers:     map[string]string{"Content-Encoding": "snappy", "X-Prometheus-Remote-Write-Version": "0.1.0"},
+		encode:      encodeRemoteWrite,
+	},
+	"influx": {
+		contentType: "text/plain; charset=utf-8",
+		encode:      encodeInflux,
+	},
+}
+
+// pusher periodically pushes all metrics to BAMBULABS_PUSH_URL for sites
+// where no Prometheus can scrape the exporter. Snapshots that could not be
+// sent are buffered and sent in order once the endpoint is back.
+type pusher struct {
+	exporter *Exporter
+	client   *http.Client
+	format   pushFormat
+	buffer   []pushBatch
+}
+
+// StartPush starts pushing metrics every BAMBULABS_PUSH_INTERVAL. It does
+// nothing when BAMBULABS_PUSH_URL is not set.
+func (e *Exporter) StartPush() {
+	if e.config.PushURL == "" {
+		return
+	}
+
+	p, err := e.newPusher()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Pushing metrics to %s every %s\n", e.config.PushURL, e.config.PushInterval)
+	go func() {
+		for now := range time.Tick(e.config.PushInterval) {
+			p.push(now)
+		}
+	}()
+}
+
+func (e *Exporter) newPusher() (*pusher, error) {
+	format, ok := pushFormats[e.config.PushFormat]
+	if !ok {
+		return nil, fmt.Errorf("unknown BAMBULABS_PUSH_FORMAT %q, expected remote_write or influx", e.config.PushFormat)
+	}
+	if e.config.PushInterval <= 0 {
+		return nil, fmt.Errorf("BAMBULABS_PUSH_INTERVAL must be positive, got %s", e.config.PushInterval)
+	}
+	return &pusher{exporter: e, client: &http.Client{}, format: format}, nil
+}
+
+// push buffers a snapshot of the metrics and sends the buffered snapshots,
+// oldest first. It stops at the first snapshot that fails and keeps it for
+// the next push. When the buffer is full the oldest snapshot is dropped.
+func (p *pusher) push(now time.Time) {
+	e := p.exporter
+
+	batch, err := p.snapshot(now)
+	if err != nil {
+		fmt.Printf("Error gathering metrics to push: %s\n", err)
+		return
+	}
+	p.buffer = append(p.buffer, batch)
+	if size := max(e.config.PushBuffer, 1); len(p.buffer) > size {
+		p.buffer = p.buffer[len(p.buffer)-size:]
+		e.pushesMetric.WithLabelValues("dropped").Inc()
+	}
+
+	for len(p.buffer) > 0 {
+		err := p.send(p.buffer[0])
+		var permanent permanentError
+		switch {
+		case errors.As(err, &permanent):
+			fmt.Printf("Dropping metrics pushed to %s: %s\n", e.config.PushURL, err)
+			e.pushesMetric.WithLabelValues("failed").Inc()
+		case err != nil:
+			fmt.Printf("Error pushing metrics to %s, %d snapshots buffered: %s\n", e.config.PushURL, len(p.buffer), err)
+			e.pushesMetric.WithLabelValues("retried").Inc()
+			e.pushBufferedMetric.Set(float64(len(p.buffer)))
+			return
+		default:
+			e.pushesMetric.WithLabelValues("sent").Inc()
+		}
+		p.buffer = p.buffer[1:]
+	}
+	e.pushBufferedMetric.Set(0)
+}
+
+// snapshot gathers the gauges and counters in the registry.
+func (p *pusher) snapshot(now time.Time) (pushBatch, error) {
+	families, err := p.exporter.registry.Gather()
+	if err != nil {
+		return pushBatch{}, err
+	}
+
+	batch := pushBatch{time: now}
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			s := sample{name: family.GetName(), labels: metric.Label}
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				s.value = metric.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				s.value = metric.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				s.value = metric.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			batch.samples = append(batch.samples, s)
+		}
+	}
+	return batch, nil
+}
+
+func (p *pusher) send(batch pushBatch) error {
+	headers := maps.Clone(p.format.headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	maps.Copy(headers, p.exporter.config.PushHeaders)
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	return postNotification(ctx, p.client, p.exporter.config.PushURL, p.format.contentType, p.format.encode(batch), headers)
+}
+
+// encodeRemoteWrite encodes a batch as a Prometheus remote write 1.0
+// WriteRequest. The protobuf is small enough to write by hand.
+func encodeRemoteWrite(batch pushBatch) []byte {
+	var request []byte
+	for _, s := range batch.samples {
+		var series []byte
+		series = appendRemoteWriteLabel(series, "__name__", s.name)
+		for _, label := range s.labels {
+			series = appendRemoteWriteLabel(series, label.GetName(), label.GetValue())
+		}
+
+		var point []byte
+		point = protowire.AppendTag(point, 1, protowire.Fixed64Type)
+		point = protowire.AppendFixed64(point, math.Float64bits(s.value))
+		point = protowire.AppendTag(point, 2, protowire.VarintType)
+		point = protowire.AppendVarint(point, uint64(batch.time.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, point)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+	return snappy.Encode(nil, request)
+}
+
+func appendRemoteWriteLabel(series []byte, name, value string) []byte {
+	var label []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, name)
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, value)
+
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	return protowire.AppendBytes(series, label)
+}
+
+// influxEscaper escapes measurement names, tag keys and tag values.
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// encodeInflux encodes a batch in InfluxDB line protocol, one line per
+// sample with the labels as tags and the value in the value field, e.g.
+//
+//	bambulabs_fan_speed_percent,fan=aux value=60 1700000000000000000
+func encodeInflux(batch pushBatch) []byte {
+	var body []byte
+	timestamp := strconv.FormatInt(batch.time.UnixNano(), 10)
+	for _, s := range batch.samples {
+		if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+			// Line protocol cannot represent them.
+			continue
+		}
+
+		body = append(body, influxEscaper.Replace(s.name)...)
+		for _, label := range s.labels {
+			if label.GetValue() == "" {
+				continue
+			}
+			body = append(body, ',')
+			body = append(body, influxEscaper.Replace(label.GetName())...)
+			body = append(body, '=')
+			body = append(body, influxEscaper.Replace(label.GetValue())...)
+		}
+		body = append(body, " value="...)
+		body = strconv.AppendFloat(body, s.value, 'g', -1, 64)
+		body = append(body, ' ')
+		body = append(body, timestamp...)
+		body = append(body, '\n')
+	}
+	return body
+}
@@ -0,0 +1,232 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+var pushTime = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestPusher returns a pusher for an exporter that has seen a report.
+func newTestPusher(t *testing.T, env map[string]string) (*Exporter, *pusher) {
+	t.Helper()
+
+	exporter := newTestExporter(t, env)
+	report := `{"print": {"command": "push_status", "layer_num": 12, "big_fan1_speed": "9"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	p, err := exporter.newPusher()
+	if err != nil {
+		t.Fatalf("Failed to create pusher: %v", err)
+	}
+	return exporter, p
+}
+
+// decodeRemoteWrite decodes a snappy compressed WriteRequest into its
+// samples keyed by series, e.g. bambulabs_fan_speed_percent{fan="aux"}.
+func decodeRemoteWrite(t *testing.T, body []byte) map[string][2]float64 {
+	t.Helper()
+
+	request, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	// fields returns the fields of a message by number.
+	fields := func(message []byte) map[protowire.Number][][]byte {
+		result := map[protowire.Number][][]byte{}
+		for len(message) > 0 {
+			number, typ, n := protowire.ConsumeTag(message)
+			message = message[n:]
+			var value []byte
+			switch typ {
+			case protowire.BytesType:
+				value, n = protowire.ConsumeBytes(message)
+			default:
+				n = protowire.ConsumeFieldValue(number, typ, message)
+				value = message[:n]
+			}
+			if n < 0 {
+				t.Fatalf("Invalid protobuf")
+			}
+			result[number] = append(result[number], value)
+			message = message[n:]
+		}
+		return result
+	}
+
+	samples := map[string][2]float64{}
+	for _, series := range fields(request)[1] {
+		seriesFields := fields(series)
+
+		var name string
+		var labels []string
+		for _, label := range seriesFields[1] {
+			labelFields := fields(label)
+			key, value := string(labelFields[1][0]), string(labelFields[2][0])
+			if key == "__name__" {
+				name = value
+			} else {
+				labels = append(labels, fmt.Sprintf("%s=%q", key, value))
+			}
+		}
+
+		point := fields(seriesFields[2][0])
+		value, _ := protowire.ConsumeFixed64(point[1][0])
+		timestamp, _ := protowire.ConsumeVarint(point[2][0])
+		samples[name+"{"+strings.Join(labels, ",")+"}"] = [2]float64{math.Float64frombits(value), float64(timestamp)}
+	}
+	return samples
+}
+
+func TestPushRemoteWrite(t *testing.T) {
+	server, requests, bodies := webhookReceiver(t, http.StatusNoContent)
+	_, p := newTestPusher(t, map[string]string{
+		"BAMBULABS_PUSH_URL":     server.URL,
+		"BAMBULABS_PUSH_HEADERS": "Authorization:Bearer secret",
+	})
+
+	p.push(pushTime)
+
+	request := <-requests
+	for header, expected := range map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+		"Authorization":                     "Bearer secret",
+	} {
+		if value := request.Header.Get(header); value != expected {
+			t.Errorf("Expected header %s: %s, got %s", header, expected, value)
+		}
+	}
+
+	samples := decodeRemoteWrite(t, <-bodies)
+	millis := float64(pushTime.UnixMilli())
+	if sample := samples["bambulabs_layer_number{}"]; sample != [2]float64{12, millis} {
+		t.Errorf("Expected layer 12 at %v, got %v", millis, sample)
+	}
+	if sample := samples[`bambulabs_fan_speed_percent{fan="aux"}`]; sample != [2]float64{60, millis} {
+		t.Errorf("Expected aux fan 60%% at %v, got %v", millis, sample)
+	}
+}
+
+func TestPushInflux(t *testing.T) {
+	server, requests, bodies := webhookReceiver(t, http.StatusNoContent)
+	_, p := newTestPusher(t, map[string]string{
+		"BAMBULABS_TOPIC":       "device/00M00A000000000/report",
+		"BAMBULABS_PUSH_URL":    server.URL + "/api/v2/write?bucket=printers&precision=ns",
+		"BAMBULABS_PUSH_FORMAT": "influx",
+	})
+
+	p.push(pushTime)
+
+	request := <-requests
+	if request.URL.Query().Get("bucket") != "printers" || request.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Unexpected request %s with content type %s", request.URL, request.Header.Get("Content-Type"))
+	}
+	lines := strings.Split(string(<-bodies), "\n")
+	for _, expected := range []string{
+		"bambulabs_layer_number value=12 1735732800000000000",
+		"bambulabs_fan_speed_percent,fan=aux value=60 1735732800000000000",
+		"bambulabs_printer_info,model=X1C,serial=00M00A000000000 value=1 1735732800000000000",
+	} {
+		found := false
+		for _, line := range lines {
+			found = found || line == expected
+		}
+		if !found {
+			t.Errorf("Expected line %q", expected)
+		}
+	}
+}
+
+func TestEncodeInfluxEscapes(t *testing.T) {
+	body := encodeInflux(pushBatch{time: time.Unix(1, 0), samples: []sample{
+		{name: "bambulabs_job_info", labels: []*dto.LabelPair{
+			{Name: proto.String("file"), Value: proto.String("my benchy,v2.3mf")},
+			{Name: proto.String("subtask"), Value: proto.String("")},
+		}, value: 1},
+		{name: "bambulabs_print_eta_timestamp_seconds", value: math.NaN()},
+	}})
+	if expected := "bambulabs_job_info,file=my\\ benchy\\,v2.3mf value=1 1000000000\n"; string(body) != expected {
+		t.Errorf("Expected %q, got %q", expected, body)
+	}
+}
+
+func TestPushBuffers(t *testing.T) {
+	server, _, bodies := webhookReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusNoContent)
+	exporter, p := newTestPusher(t, map[string]string{
+		"BAMBULABS_PUSH_URL":    server.URL,
+		"BAMBULABS_PUSH_FORMAT": "influx",
+	})
+
+	for i := range 3 {
+		p.push(pushTime.Add(time.Duration(i) * time.Second))
+		if i < 2 {
+			if value := testutil.ToFloat64(exporter.pushBufferedMetric); value != float64(i+1) {
+				t.Errorf("Expected %d buffered snapshots, got %v", i+1, value)
+			}
+		}
+	}
+
+	// Two failed attempts, then the three snapshots in order.
+	<-bodies
+	<-bodies
+	for i := range 3 {
+		timestamp := fmt.Sprint(pushTime.Add(time.Duration(i) * time.Second).UnixNano())
+		if body := string(<-bodies); !strings.Contains(body, "bambulabs_layer_number value=12 "+timestamp) {
+			t.Errorf("Expected snapshot %d at %s, got %s", i, timestamp, body)
+		}
+	}
+	if value := testutil.ToFloat64(exporter.pushBufferedMetric); value != 0 {
+		t.Errorf("Expected an empty buffer, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.pushesMetric.WithLabelValues("sent")); value != 3 {
+		t.Errorf("Expected 3 sent snapshots, got %v", value)
+	}
+}
+
+func TestPushBufferLimit(t *testing.T) {
+	server, _, _ := webhookReceiver(t, http.StatusServiceUnavailable)
+	exporter, p := newTestPusher(t, map[string]string{
+		"BAMBULABS_PUSH_URL":    server.URL,
+		"BAMBULABS_PUSH_BUFFER": "2",
+	})
+
+	for i := range 3 {
+		p.push(pushTime.Add(time.Duration(i) * time.Second))
+	}
+
+	if len(p.buffer) != 2 || !p.buffer[0].time.Equal(pushTime.Add(time.Second)) {
+		t.Errorf("Expected the 2 newest snapshots to be buffered, got %d", len(p.buffer))
+	}
+	if value := testutil.ToFloat64(exporter.pushesMetric.WithLabelValues("dropped")); value != 1 {
+		t.Errorf("Expected 1 dropped snapshot, got %v", value)
+	}
+}
+
+func TestPushUnknownFormat(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_PUSH_FORMAT": "graphite"})
+	if _, err := exporter.newPusher(); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestPushInvalidInterval(t *testing.T) {
+	for _, interval := range []string{"0s", "-1m"} {
+		exporter := newTestExporter(t, map[string]string{"BAMBULABS_PUSH_INTERVAL": interval})
+		if _, err := exporter.newPusher(); err == nil {
+			t.Errorf("Expected an error for interval %s", interval)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recording is a line of a recording file.
+type recording struct {
+	Time  time.Time `json:"time"`
+	Topic string    `json:"topic"`
+	// Payload is the message as JSON, or a JSON string if the message was
+	// not valid JSON.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// redacted replaces the values of secrets in recorded messages.
+const redacted = "REDACTED"
+
+// secretKeys are keys whose values are redacted in recordings, in addition to
+// URLs, which can carry signed tokens. Keys ending in one of them, e.g.
+// dev_access_code, are redacted too.
+var secretKeys = map[string]bool{
+	"access_code": true,
+	"password":    true,
+	"passwd":      true,
+	"token":       true,
+	"auth":        true,
+	"sign":        true,
+	"ttcode":      true,
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "url" || strings.HasSuffix(key, "_url") {
+		return true
+	}
+	for secret := range secretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder appends every message received from the printer to the JSONL
+// file in BAMBULABS_RECORD_FILE, so real payloads can be replayed later.
+type recorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newRecorder(path string) (*recorder, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening BAMBULABS_RECORD_FILE: %w", err)
+	}
+	return &recorder{file: file}, nil
+}
+
+// record writes a message with secrets redacted.
+func (r *recorder) record(now time.Time, topic string, payload []byte) {
+	line, err := json.Marshal(recording{Time: now, Topic: topic, Payload: redact(payload)})
+	if err != nil {
+		fmt.Printf("Error recording message: %s\n", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		fmt.Printf("Error recording message: %s\n", err)
+	}
+}
+
+// redact returns the payload with the values of secret keys replaced.
+func redact(payload []byte) json.RawMessage {
+	var message any
+	if err := json.Unmarshal(payload, &message); err != nil {
+		quoted, _ := json.Marshal(string(payload))
+		return quoted
+	}
+
+	result, err := json.Marshal(redactValue(message))
+	if err != nil {
+		quoted, _ := json.Marshal(string(payload))
+		return quoted
+	}
+	return result
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if _, ok := field.(string); ok && isSecretKey(key) && field != "" {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+// replayMessage is a recorded message passed to messagePubHandler.
+type replayMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m *replayMessage) Duplicate() bool {
+	return false
+}
+
+func (m *replayMessage) Qos() byte {
+	return 0
+}
+
+func (m *replayMessage) Retained() bool {
+	return false
+}
+
+func (m *replayMessage) Topic() string {
+	return m.topic
+}
+
+func (m *replayMessage) MessageID() uint16 {
+	return 0
+}
+
+func (m *replayMessage) Payload() []byte {
+	return m.payload
+}
+
+func (m *replayMessage) Ack() {
+}
+
+// Replay feeds a recording through messagePubHandler, keeping the time
+// between messages divided by speed. A speed of 0 replays as fast as
+// possible. Without BAMBULABS_TOPIC the printer is detected from the topic
+// of the first message.
+//
+// Replayed messages are not recorded again, which would never end when the
+// recording being replayed is BAMBULABS_RECORD_FILE, and events of the past
+// are not sent to notifiers or Home Assistant.
+func (e *Exporter) Replay(path string, speed float64) error {
+	if e.recorder != nil {
+		e.recorder.file.Close()
+		e.recorder = nil
+	}
+	e.notifiers, e.homeAssistant = nil, nil
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// Full status reports are larger than the default limit.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var last time.Time
+	for line := 1; scanner.Scan(); line++ {
+		var r recording
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		if e.config.Topic == "" && r.Topic != "" {
+			e.config.Topic = r.Topic
+			e.setModel(e.detectModel())
+		}
+		if speed > 0 && !last.IsZero() && r.Time.After(last) {
+			time.Sleep(time.Duration(float64(r.Time.Sub(last)) / speed))
+		}
+		last = r.Time
+
+		payload := []byte(r.Payload)
+		var text string
+		if json.Unmarshal(r.Payload, &text) == nil {
+			payload = []byte(text)
+		}
+		e.messagePubHandler(nil, &replayMessage{topic: r.Topic, payload: payload})
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// readRecording returns the lines of a recording file.
+func readRecording(t *testing.T, path string) []recording {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open recording: %v", err)
+	}
+	defer file.Close()
+
+	var recordings []recording
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r recording
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Failed to unmarshal recording %s: %v", scanner.Text(), err)
+		}
+		recordings = append(recordings, r)
+	}
+	return recordings
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_RECORD_FILE": path})
+	exporter.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	report := `{"print": {"command": "push_status", "layer_num": 3, "upload": {"oss_url": "https://bucket/file?Signature=abc", "message": ""}, "ipcam": {"rtsp_url": "rtsps://10.0.0.2/streaming/live/1"}, "dev_access_code": "12345678"}}`
+	exporter.messagePubHandler(nil, &replayMessage{topic: "device/test123/report", payload: []byte(report)})
+	exporter.messagePubHandler(nil, &replayMessage{topic: "device/test123/report", payload: []byte("not json")})
+
+	recordings := readRecording(t, path)
+	if len(recordings) != 2 {
+		t.Fatalf("Expected 2 recorded messages, got %d", len(recordings))
+	}
+	if recordings[0].Topic != "device/test123/report" || !recordings[0].Time.Equal(exporter.now()) {
+		t.Errorf("Unexpected recording: %+v", recordings[0])
+	}
+	payload := string(recordings[0].Payload)
+	if strings.Contains(payload, "Signature") || strings.Contains(payload, "rtsps") || strings.Contains(payload, "12345678") {
+		t.Errorf("Expected URLs to be redacted, got %s", payload)
+	}
+	if !strings.Contains(payload, `"layer_num":3`) || !strings.Contains(payload, `"oss_url":"REDACTED"`) {
+		t.Errorf("Expected the report with redacted URLs, got %s", payload)
+	}
+	if payload := string(recordings[1].Payload); payload != `"not json"` {
+		t.Errorf("Expected invalid JSON to be recorded as a string, got %s", payload)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var lines []string
+	for i, report := range []string{
+		`{"print": {"command": "push_status", "layer_num": 1, "nozzle_temper": 190}}`,
+		`{"print": {"command": "push_status", "layer_num": 2, "nozzle_temper": 200}}`,
+	} {
+		line, _ := json.Marshal(recording{Time: start.Add(time.Duration(i) * 200 * time.Millisecond), Topic: "device/00M00A000000000/report", Payload: json.RawMessage(report)})
+		lines = append(lines, string(line))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write recording: %v", err)
+	}
+
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_TOPIC": ""})
+	began := time.Now()
+	if err := exporter.Replay(path, 10); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if elapsed := time.Since(began); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the replay to take 20ms at 10x, took %s", elapsed)
+	}
+
+	if value := testutil.ToFloat64(exporter.layerNumberMetric); value != 2 {
+		t.Errorf("Expected layer 2, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.nozzleTemperMetric); value != 200 {
+		t.Errorf("Expected nozzle temperature 200, got %v", value)
+	}
+	if exporter.model != ModelX1C {
+		t.Errorf("Expected the model to be detected from the recorded topic, got %s", exporter.model)
+	}
+}
+
+func TestReplayWithoutSideEffects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	server, requests, _ := webhookReceiver(t, http.StatusOK)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_RECORD_FILE":  path,
+		"BAMBULABS_WEBHOOK_URLS": server.URL,
+	})
+
+	// Record a job starting, then replay the recording into itself.
+	for _, report := range []string{
+		`{"print": {"command": "push_status", "gcode_state": "IDLE"}}`,
+		`{"print": {"command": "push_status", "gcode_state": "RUNNING", "subtask_name": "benchy"}}`,
+	} {
+		exporter.messagePubHandler(nil, &replayMessage{topic: "device/test123/report", payload: []byte(report)})
+	}
+	exporter.deliveries.Wait()
+	<-requests
+
+	exporter = newTestExporter(t, map[string]string{
+		"BAMBULABS_RECORD_FILE":  path,
+		"BAMBULABS_WEBHOOK_URLS": server.URL,
+	})
+	if err := exporter.Replay(path, 0); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	exporter.deliveries.Wait()
+
+	if recordings := readRecording(t, path); len(recordings) != 2 {
+		t.Errorf("Expected the replay not to be recorded, got %d recorded messages", len(recordings))
+	}
+	if len(requests) != 0 {
+		t.Errorf("Expected no notifications for replayed events, got %d", len(requests))
+	}
+}
+
+func TestReplayInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	if err := os.WriteFile(path, []byte("{}\nnot json\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write recording: %v", err)
+	}
+
+	exporter := newTestExporter(t, nil)
+	if err := exporter.Replay(path, 0); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("Expected an error for line 2, got %v", err)
+	}
+}
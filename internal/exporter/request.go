@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// infoRequest is the payload used to query the printer for static
+// information such as firmware versions.
+type infoRequest struct {
+	Info struct {
+		SequenceID string `json:"sequence_id"`
+		Command    string `json:"command"`
+	} `json:"info"`
+}
+
+// ledControlRequest is the payload of the system.ledctrl command.
+type ledControlRequest struct {
+	System struct {
+		SequenceID   string `json:"sequence_id"`
+		Command      string `json:"command"`
+		LedNode      string `json:"led_node"`
+		LedMode      string `json:"led_mode"`
+		LedOnTime    int    `json:"led_on_time"`
+		LedOffTime   int    `json:"led_off_time"`
+		LoopTimes    int    `json:"loop_times"`
+		IntervalTime int    `json:"interval_time"`
+	} `json:"system"`
+}
+
+// printRequest is the payload of the print commands that take at most a
+// single parameter, e.g. pause, resume, stop and print_speed.
+type printRequest struct {
+	Print struct {
+		SequenceID string `json:"sequence_id"`
+		Command    string `json:"command"`
+		Param      string `json:"param"`
+	} `json:"print"`
+}
+
+// requestTopic returns the topic the printer listens on for commands. It is
+// derived from the report topic, e.g. device/<serial>/report becomes
+// device/<serial>/request.
+func (e *Exporter) requestTopic() string {
+	return strings.TrimSuffix(e.config.Topic, "/report") + "/request"
+}
+
+// nextSequenceID returns a new sequence id for a request. The printer echoes
+// it back in its reply.
+func (e *Exporter) nextSequenceID() string {
+	return strconv.FormatUint(e.sequenceID.Add(1), 10)
+}
+
+// publishRequest marshals request and publishes it to the printer's request
+// topic.
+func (e *Exporter) publishRequest(client mqtt.Client, request any) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("marshalling request: %w", err)
+	}
+
+	token := client.Publish(e.requestTopic(), 0, false, payload)
+	token.Wait()
+	return token.Error()
+}
+
+// requestVersion asks the printer to report the firmware and hardware
+// versions of all of its modules.
+func (e *Exporter) requestVersion(client mqtt.Client) error {
+	request := infoRequest{}
+	request.Info.SequenceID = e.nextSequenceID()
+	request.Info.Command = "get_version"
+	return e.publishRequest(client, request)
+}
+
+// setLight switches the light node on or off.
+func (e *Exporter) setLight(client mqtt.Client, node, mode string) error {
+	request := ledControlRequest{}
+	request.System.SequenceID = e.nextSequenceID()
+	request.System.Command = "ledctrl"
+	request.System.LedNode = node
+	request.System.LedMode = mode
+	request.System.LedOnTime = 500
+	request.System.LedOffTime = 500
+	return e.publishRequest(client, request)
+}
+
+// sendPrintCommand sends a print command such as pause, resume or stop.
+func (e *Exporter) sendPrintCommand(client mqtt.Client, command, param string) error {
+	request := printRequest{}
+	request.Print.SequenceID = e.nextSequenceID()
+	request.Print.Command = command
+	request.Print.Param = param
+	return e.publishRequest(client, request)
+}
+
+// setSpeedLevel changes the print speed level, see speedLevels.
+func (e *Exporter) setSpeedLevel(client mqtt.Client, level int) error {
+	return e.sendPrintCommand(client, "print_speed", strconv.Itoa(level))
+}
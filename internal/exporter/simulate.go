@@ -0,0 +1,460 @@
+package exporter
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// SimulatorConfig configures a simulated printer.
+type SimulatorConfig struct {
+	// Address is the address the MQTT broker listens on, e.g. :8883.
+	Address string
+	Model   PrinterModel
+	// Serial defaults to a serial number of the model.
+	Serial     string
+	AccessCode string
+	// Interval is the time between reports.
+	Interval time.Duration
+	// Layers is the number of layers of each simulated print.
+	Layers int
+	// Scenario is finish, failure or cycle, which alternates between them.
+	Scenario string
+}
+
+// simulatorScenarios lists the supported scenarios.
+var simulatorScenarios = []string{"cycle", "finish", "failure"}
+
+// Phases of a simulated print job.
+const (
+	phaseIdle = iota
+	phasePrepare
+	phaseRunning
+	phaseDone
+)
+
+const (
+	ambientTemperature     = 25.0
+	simulatedBedTarget     = 55.0
+	simulatedNozzleTarget  = 220.0
+	simulatedChamberTarget = 38.0
+	simulatedTrayCount     = 4
+	simulatedIdleTicks     = 3
+	simulatedDoneTicks     = 3
+	simulatedHMSTicks      = 3
+	simulatedLayerSeconds  = 90 // seconds the printer estimates per layer
+)
+
+// Codes raised by simulated prints: a nozzle temperature warning half way
+// through and the print error of a failed print.
+var (
+	simulatedHMS        = hmsEntry{Attr: NewNumber(0x03000100), Code: NewNumber(0x00010007)}
+	simulatedPrintError = 0x0300400C
+)
+
+// simulatedTrays is the filament loaded in each tray of the simulated AMS.
+var simulatedTrays = [simulatedTrayCount]struct {
+	trayType string
+	color    string
+}{
+	{"PLA", "FFFFFFFF"},
+	{"PLA", "000000FF"},
+	{"PETG", "FF6A13FF"},
+	{"PLA", "0086D6FF"},
+}
+
+// Simulator emulates a printer over a local MQTT broker. It publishes
+// push_status reports of print jobs that heat up, print layers, swap AMS
+// trays, raise HMS errors and finish or fail, and answers get_version, so the
+// exporter and alert rules can be tested without hardware.
+type Simulator struct {
+	config   SimulatorConfig
+	features modelFeatures
+	server   *mochi.Server
+	listener *listeners.TCP
+
+	mu         sync.Mutex
+	sequenceID int
+	job        int
+	phase      int
+	ticks      int
+	failed     bool
+	layer      int
+	nozzle     float64
+	bed        float64
+	chamber    float64
+	trayNow    int
+	remain     [simulatedTrayCount]float64
+}
+
+// NewSimulator starts the MQTT broker of a simulated printer.
+func NewSimulator(config SimulatorConfig) (*Simulator, error) {
+	if !slices.Contains(simulatorScenarios, config.Scenario) {
+		return nil, fmt.Errorf("unknown scenario %q, expected one of %v", config.Scenario, simulatorScenarios)
+	}
+	if config.Layers < 1 {
+		return nil, fmt.Errorf("layers must be at least 1, got %d", config.Layers)
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", config.Interval)
+	}
+	features, ok := modelFeatureSet[config.Model]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %q", config.Model)
+	}
+	if config.Serial == "" {
+		config.Serial = simulatedSerial(config.Model)
+	}
+
+	s := &Simulator{
+		config:   config,
+		features: features,
+		nozzle:   ambientTemperature,
+		bed:      ambientTemperature,
+		chamber:  ambientTemperature,
+		// The last tray is nearly empty, so the first print reports low
+		// filament.
+		remain: [simulatedTrayCount]float64{100, 80, 45, 12},
+	}
+
+	cert, err := selfSignedCertificate(config.Serial)
+	if err != nil {
+		return nil, fmt.Errorf("generating certificate: %w", err)
+	}
+	s.server = mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	})
+	if err := s.server.AddHook(&simulatorAuth{accessCode: config.AccessCode}, nil); err != nil {
+		return nil, err
+	}
+	s.listener = listeners.NewTCP(listeners.Config{
+		ID:        "simulator",
+		Address:   config.Address,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	})
+	if err := s.server.AddListener(s.listener); err != nil {
+		return nil, err
+	}
+	if err := s.server.Subscribe(s.topic("request"), 1, s.handleRequest); err != nil {
+		return nil, err
+	}
+	if err := s.server.Serve(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// simulatedSerial returns a serial number that the exporter detects as the
+// model.
+func simulatedSerial(model PrinterModel) string {
+	prefix := "SIM"
+	for serialPrefix, m := range serialPrefixes {
+		if m == model {
+			prefix = serialPrefix
+		}
+	}
+	return prefix + "00A000000000"
+}
+
+// Address returns the address the broker listens on.
+func (s *Simulator) Address() string {
+	return s.listener.Address()
+}
+
+// Serial returns the serial number of the simulated printer.
+func (s *Simulator) Serial() string {
+	return s.config.Serial
+}
+
+// Close stops the broker.
+func (s *Simulator) Close() error {
+	return s.server.Close()
+}
+
+// Run publishes a report every interval until the broker is closed.
+func (s *Simulator) Run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		s.step()
+		err := s.publish(s.report())
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Simulator) topic(kind string) string {
+	return "device/" + s.config.Serial + "/" + kind
+}
+
+func (s *Simulator) publish(message any) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return s.server.Publish(s.topic("report"), payload, false, 0)
+}
+
+// fails reports whether the current job fails.
+func (s *Simulator) fails() bool {
+	switch s.config.Scenario {
+	case "failure":
+		return true
+	case "cycle":
+		return s.job%2 == 0
+	default:
+		return false
+	}
+}
+
+// step advances the simulation by one report.
+func (s *Simulator) step() {
+	s.ticks++
+	switch s.phase {
+	case phaseIdle:
+		if s.ticks >= simulatedIdleTicks {
+			s.job++
+			s.phase, s.ticks = phasePrepare, 0
+			s.layer, s.failed = 0, false
+		}
+	case phasePrepare:
+		if math.Abs(s.nozzle-simulatedNozzleTarget) < 2 && math.Abs(s.bed-simulatedBedTarget) < 2 {
+			s.phase, s.ticks = phaseRunning, 0
+		}
+	case phaseRunning:
+		s.layer++
+		// Each layer uses a percent of the active spool, which is replaced
+		// once it runs out.
+		if s.remain[s.trayNow]--; s.remain[s.trayNow] <= 0 {
+			s.remain[s.trayNow] = 100
+		}
+		// Swap trays four times per print.
+		if swap := max(s.config.Layers/4, 1); s.layer%swap == 0 {
+			s.trayNow = (s.trayNow + 1) % simulatedTrayCount
+		}
+
+		switch {
+		case s.fails() && s.layer >= s.config.Layers*6/10:
+			s.phase, s.ticks, s.failed = phaseDone, 0, true
+		case s.layer >= s.config.Layers:
+			s.phase, s.ticks = phaseDone, 0
+		}
+	case phaseDone:
+		if s.ticks >= simulatedDoneTicks {
+			s.phase, s.ticks = phaseIdle, 0
+		}
+	}
+
+	nozzleTarget, bedTarget, chamberTarget := s.targets()
+	s.nozzle = approach(s.nozzle, nozzleTarget)
+	s.bed = approach(s.bed, bedTarget)
+	s.chamber = approach(s.chamber, chamberTarget)
+}
+
+// targets returns the nozzle, bed and chamber temperatures the printer heats
+// up to or cools down to in the current phase.
+func (s *Simulator) targets() (nozzle, bed, chamber float64) {
+	if s.phase == phasePrepare || s.phase == phaseRunning {
+		return simulatedNozzleTarget, simulatedBedTarget, simulatedChamberTarget
+	}
+	return ambientTemperature, ambientTemperature, ambientTemperature
+}
+
+// approach moves a temperature a third of the way to its target.
+func approach(current, target float64) float64 {
+	next := current + (target-current)/3
+	if math.Abs(target-next) < 0.5 {
+		next = target
+	}
+	return math.Round(next*10) / 10
+}
+
+// gcodeState returns the gcode_state of the current phase.
+func (s *Simulator) gcodeState() string {
+	switch s.phase {
+	case phasePrepare:
+		return "PREPARE"
+	case phaseRunning:
+		return "RUNNING"
+	case phaseDone:
+		if s.failed {
+			return "FAILED"
+		}
+		return "FINISH"
+	default:
+		return "IDLE"
+	}
+}
+
+// report returns a full push_status report of the current state.
+func (s *Simulator) report() map[string]any {
+	s.sequenceID++
+	printing := s.phase == phaseRunning
+	nozzleTarget, bedTarget := 0.0, 0.0
+	if s.phase == phasePrepare || printing {
+		nozzleTarget, bedTarget = simulatedNozzleTarget, simulatedBedTarget
+	}
+
+	percent, remaining := 0, 0
+	if s.phase == phaseRunning || s.phase == phaseDone {
+		percent = s.layer * 100 / s.config.Layers
+		remaining = (s.config.Layers - s.layer) * simulatedLayerSeconds / 60
+	}
+	if s.phase == phaseDone && !s.failed {
+		percent, remaining = 100, 0
+	}
+
+	fan := "0"
+	if printing {
+		fan = "15"
+	}
+
+	status := map[string]any{
+		"command":              "push_status",
+		"sequence_id":          strconv.Itoa(s.sequenceID),
+		"gcode_state":          s.gcodeState(),
+		"subtask_name":         fmt.Sprintf("simulated_job_%d", s.job),
+		"gcode_file":           fmt.Sprintf("/data/Metadata/simulated_job_%d.gcode", s.job),
+		"task_id":              strconv.Itoa(s.job),
+		"print_type":           "local",
+		"mc_percent":           percent,
+		"mc_remaining_time":    remaining,
+		"layer_num":            s.layer,
+		"total_layer_num":      s.config.Layers,
+		"nozzle_temper":        s.nozzle,
+		"nozzle_target_temper": nozzleTarget,
+		"bed_temper":           s.bed,
+		"bed_target_temper":    bedTarget,
+		"cooling_fan_speed":    fan,
+		"heatbreak_fan_speed":  "15",
+		"spd_lvl":              2,
+		"spd_mag":              100,
+		"wifi_signal":          "-52dBm",
+		"print_error":          0,
+		"hms":                  []hmsEntry{},
+		"lights_report":        []map[string]string{{"node": "chamber_light", "mode": "on"}},
+		"sdcard":               true,
+	}
+	if s.features.chamberTemperature {
+		status["chamber_temper"] = s.chamber
+	}
+	if s.features.auxFan {
+		status["big_fan1_speed"] = fan
+	}
+	if s.features.chamberFan {
+		status["big_fan2_speed"] = fan
+	}
+	if printing && s.layer > s.config.Layers/2 && s.layer <= s.config.Layers/2+simulatedHMSTicks {
+		status["hms"] = []hmsEntry{simulatedHMS}
+	}
+	if s.phase == phaseDone && s.failed {
+		status["print_error"] = simulatedPrintError
+	}
+
+	trays := make([]map[string]any, 0, simulatedTrayCount)
+	for i, tray := range simulatedTrays {
+		trays = append(trays, map[string]any{
+			"id":         strconv.Itoa(i),
+			"tray_type":  tray.trayType,
+			"tray_color": tray.color,
+			"remain":     int(s.remain[i]),
+		})
+	}
+	ams := map[string]any{"id": "0", "tray": trays}
+	if s.features.amsEnvironment {
+		ams["humidity"] = "4"
+		ams["temp"] = "24.5"
+	}
+	trayNow := "255"
+	if printing {
+		trayNow = strconv.Itoa(s.trayNow)
+	}
+	status["ams"] = map[string]any{"ams": []any{ams}, "tray_now": trayNow}
+
+	return map[string]any{"print": status}
+}
+
+// handleRequest answers get_version requests and pushall requests, which
+// ask for a full report.
+func (s *Simulator) handleRequest(cl *mochi.Client, sub packets.Subscription, pk packets.Packet) {
+	var request struct {
+		Info struct {
+			SequenceID string `json:"sequence_id"`
+			Command    string `json:"command"`
+		} `json:"info"`
+		Pushing struct {
+			Command string `json:"command"`
+		} `json:"pushing"`
+	}
+	if err := json.Unmarshal(pk.Payload, &request); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case request.Info.Command == "get_version":
+		s.publish(s.versionReply(request.Info.SequenceID))
+	case request.Pushing.Command == "pushall":
+		s.publish(s.report())
+	}
+}
+
+func (s *Simulator) versionReply(sequenceID string) map[string]any {
+	var projectName string
+	for name, model := range projectNames {
+		if model == s.config.Model {
+			projectName = name
+		}
+	}
+	return map[string]any{"info": map[string]any{
+		"command":     "get_version",
+		"sequence_id": sequenceID,
+		"result":      "success",
+		"reason":      "",
+		"module": []map[string]string{
+			{"name": "ota", "project_name": projectName, "sw_ver": "01.08.02.00", "hw_ver": "OTA", "sn": s.config.Serial},
+			{"name": "mc", "sw_ver": "00.00.25.00", "hw_ver": "MC07", "sn": "SIMMC0000000"},
+			{"name": "ams/0", "sw_ver": "00.00.06.40", "hw_ver": "AMS08", "sn": "SIMAMS000000"},
+		},
+	}}
+}
+
+// simulatorAuth accepts clients with the printer's username and access code.
+type simulatorAuth struct {
+	mochi.HookBase
+	accessCode string
+}
+
+func (a *simulatorAuth) ID() string {
+	return "bambulabs-simulator-auth"
+}
+
+func (a *simulatorAuth) Provides(b byte) bool {
+	return b == mochi.OnConnectAuthenticate || b == mochi.OnACLCheck
+}
+
+func (a *simulatorAuth) OnConnectAuthenticate(cl *mochi.Client, pk packets.Packet) bool {
+	return string(pk.Connect.Username) == "bblp" &&
+		subtle.ConstantTimeCompare(pk.Connect.Password, []byte(a.accessCode)) == 1
+}
+
+func (a *simulatorAuth) OnACLCheck(cl *mochi.Client, topic string, write bool) bool {
+	return true
+}
@@ -0,0 +1,124 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestSimulator(t *testing.T, config SimulatorConfig) *Simulator {
+	t.Helper()
+
+	config.Address = "127.0.0.1:0"
+	config.AccessCode = "12345678"
+	config.Interval = 10 * time.Millisecond
+	simulator, err := NewSimulator(config)
+	if err != nil {
+		t.Fatalf("Failed to start simulator: %v", err)
+	}
+	t.Cleanup(func() { simulator.Close() })
+	return simulator
+}
+
+func TestSimulatorScenario(t *testing.T) {
+	simulator := newTestSimulator(t, SimulatorConfig{Model: ModelX1C, Layers: 20, Scenario: "cycle"})
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_TOPIC": "device/" + simulator.Serial() + "/report"})
+	recorder := &recordingNotifier{}
+	exporter.notifiers = []notifier{recorder}
+
+	states := map[string]bool{}
+	var maxNozzle float64
+	for range 100 {
+		simulator.step()
+		report := simulator.report()
+		payload, _ := json.Marshal(report)
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: payload})
+
+		status := report["print"].(map[string]any)
+		states[status["gcode_state"].(string)] = true
+		maxNozzle = max(maxNozzle, status["nozzle_temper"].(float64))
+	}
+	exporter.deliveries.Wait()
+
+	for _, state := range []string{"IDLE", "PREPARE", "RUNNING", "FINISH", "FAILED"} {
+		if !states[state] {
+			t.Errorf("Expected the simulation to go through %s", state)
+		}
+	}
+	if maxNozzle < simulatedNozzleTarget-2 {
+		t.Errorf("Expected the nozzle to heat up, got at most %v", maxNozzle)
+	}
+
+	// The first job finishes after an HMS error half way through and the last
+	// tray running low, the second one fails.
+	expected := []string{EventJobStarted, EventHMSError, EventFilamentLow, EventJobFinished, EventJobStarted, EventHMSError, EventJobFailed}
+	if types := recorder.types(); len(types) < len(expected) || !slices.Equal(types[:len(expected)], expected) {
+		t.Errorf("Expected events %v, got %v", expected, types)
+	}
+}
+
+func TestSimulatorModelFeatures(t *testing.T) {
+	simulator := newTestSimulator(t, SimulatorConfig{Model: ModelA1, Layers: 10, Scenario: "finish"})
+	if serial := simulator.Serial(); modelFromSerial(serial) != ModelA1 {
+		t.Errorf("Expected an A1 serial, got %s", serial)
+	}
+
+	status := simulator.report()["print"].(map[string]any)
+	for _, key := range []string{"chamber_temper", "big_fan1_speed", "big_fan2_speed"} {
+		if _, ok := status[key]; ok {
+			t.Errorf("Expected no %s on an A1", key)
+		}
+	}
+	ams := status["ams"].(map[string]any)["ams"].([]any)[0].(map[string]any)
+	if _, ok := ams["humidity"]; ok {
+		t.Error("Expected no AMS humidity on an A1")
+	}
+}
+
+func TestSimulatorInvalidConfig(t *testing.T) {
+	for _, config := range []SimulatorConfig{
+		{Model: ModelX1C, Layers: 10, Interval: time.Second, Scenario: "explode"},
+		{Model: ModelX1C, Layers: 0, Interval: time.Second, Scenario: "finish"},
+		{Model: ModelX1C, Layers: 10, Interval: 0, Scenario: "finish"},
+		{Model: ModelX1C, Layers: 10, Interval: -time.Second, Scenario: "finish"},
+		{Model: ModelUnknown, Layers: 10, Interval: time.Second, Scenario: "finish"},
+	} {
+		if _, err := NewSimulator(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+// TestSimulatorExporter connects the exporter to the simulator over TLS like
+// to a real printer.
+func TestSimulatorExporter(t *testing.T) {
+	simulator := newTestSimulator(t, SimulatorConfig{Model: ModelP1S, Layers: 10, Scenario: "finish"})
+	go simulator.Run()
+
+	host, port, _ := net.SplitHostPort(simulator.Address())
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_IP":       host,
+		"BAMBULABS_PORT":     port,
+		"BAMBULABS_USERNAME": "bblp",
+		"BAMBULABS_PASSWORD": "12345678",
+		"BAMBULABS_TOPIC":    "device/" + simulator.Serial() + "/report",
+	})
+	exporter.ConnectToBroker()
+	t.Cleanup(func() { exporter.client.Disconnect(0) })
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(exporter.layerNumberMetric) == 0 || testutil.CollectAndCount(exporter.moduleInfoMetric) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the exporter to receive reports and versions")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if value := testutil.ToFloat64(exporter.printerInfoMetric.WithLabelValues("P1S", simulator.Serial())); value != 1 {
+		t.Errorf("Expected the printer to be detected as a P1S, got %v", value)
+	}
+}
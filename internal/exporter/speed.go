@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"time"
+)
+
+// speedLevels maps the spd_lvl values reported by the printer to the names
+// used in Bambu Studio.
+var speedLevels = map[int]string{
+	1: "silent",
+	2: "standard",
+	3: "sport",
+	4: "ludicrous",
+}
+
+// speedState tracks the speed level between reports so time spent at each
+// level can be accumulated.
+type speedState struct {
+	job      string
+	level    int
+	printing bool
+	updated  time.Time
+}
+
+// jobKey identifies a print job across reports. It is empty when the report
+// does not carry job details.
+func jobKey(data BambuLabsX1C) string {
+	if data.Print.GcodeFile == "" && data.Print.GcodeStartTime == "" {
+		return ""
+	}
+	return data.Print.GcodeFile + "@" + data.Print.GcodeStartTime
+}
+
+// updateSpeed exports the current speed level and magnitude and accounts the
+// time since the previous report to the speed level that was active while
+// printing. Levels of 0 are treated as not reported, as partial reports omit
+// unchanged fields.
+func (e *Exporter) updateSpeed(data BambuLabsX1C) {
+	now := e.now()
+
+	if e.speed.printing && e.speed.level != 0 && !e.speed.updated.IsZero() {
+		if name, ok := speedLevels[e.speed.level]; ok {
+			elapsed := now.Sub(e.speed.updated).Seconds()
+			e.speedLevelSecondsMetric.WithLabelValues(name).Add(elapsed)
+			e.jobSpeedLevelSecondsMetric.WithLabelValues(name).Add(elapsed)
+		}
+	}
+	e.speed.updated = now
+
+	if job := jobKey(data); job != "" && job != e.speed.job {
+		e.speed.job = job
+		e.jobSpeedLevelSecondsMetric.Reset()
+	}
+	if data.Print.GcodeState != "" {
+		e.speed.printing = data.Print.GcodeState == "RUNNING"
+	}
+
+	if lvl := data.Print.SpdLvl.Int(); lvl != 0 {
+		e.speed.level = lvl
+		for level, name := range speedLevels {
+			value := 0.0
+			if level == lvl {
+				value = 1
+			}
+			e.speedLevelMetric.WithLabelValues(name).Set(value)
+		}
+	}
+	if data.Print.SpdMag.Present() {
+		e.speedMagnitudeMetric.Set(data.Print.SpdMag.Float64())
+	}
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSpeedMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	clock := time.Unix(1700000000, 0)
+	exporter.now = func() time.Time { return clock }
+
+	report := func(state string, level, magnitude int, file string) {
+		t.Helper()
+		start := ""
+		if file != "" {
+			start = "1700000000"
+		}
+		payload := fmt.Sprintf(`{
+			"print": {
+				"command": "push_status",
+				"gcode_state": %q,
+				"gcode_file": %q,
+				"gcode_start_time": %q,
+				"spd_lvl": %d,
+				"spd_mag": %d
+			}
+		}`, state, file, start, level, magnitude)
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(payload)})
+	}
+
+	report("RUNNING", 2, 100, "cube.gcode")
+
+	if value := testutil.ToFloat64(exporter.speedLevelMetric.WithLabelValues("standard")); value != 1 {
+		t.Errorf("Expected standard speed level 1, got %f", value)
+	}
+	if value := testutil.ToFloat64(exporter.speedLevelMetric.WithLabelValues("ludicrous")); value != 0 {
+		t.Errorf("Expected ludicrous speed level 0, got %f", value)
+	}
+	if value := testutil.ToFloat64(exporter.speedMagnitudeMetric); value != 100 {
+		t.Errorf("Expected speed magnitude 100, got %f", value)
+	}
+
+	clock = clock.Add(30 * time.Second)
+	report("RUNNING", 4, 166, "cube.gcode")
+
+	if value := testutil.ToFloat64(exporter.speedLevelMetric.WithLabelValues("ludicrous")); value != 1 {
+		t.Errorf("Expected ludicrous speed level 1, got %f", value)
+	}
+	if value := testutil.ToFloat64(exporter.speedMagnitudeMetric); value != 166 {
+		t.Errorf("Expected speed magnitude 166, got %f", value)
+	}
+
+	// Partial reports without a speed level keep the previous level.
+	clock = clock.Add(20 * time.Second)
+	report("", 0, 0, "")
+
+	clock = clock.Add(10 * time.Second)
+	report("PAUSE", 4, 166, "cube.gcode")
+
+	// Time while paused is not accounted.
+	clock = clock.Add(60 * time.Second)
+	report("RUNNING", 4, 166, "cube.gcode")
+
+	if value := testutil.ToFloat64(exporter.speedLevelSecondsMetric.WithLabelValues("standard")); value != 30 {
+		t.Errorf("Expected 30s at standard speed, got %f", value)
+	}
+	if value := testutil.ToFloat64(exporter.speedLevelSecondsMetric.WithLabelValues("ludicrous")); value != 30 {
+		t.Errorf("Expected 30s at ludicrous speed, got %f", value)
+	}
+	if value := testutil.ToFloat64(exporter.jobSpeedLevelSecondsMetric.WithLabelValues("ludicrous")); value != 30 {
+		t.Errorf("Expected 30s at ludicrous speed for the job, got %f", value)
+	}
+
+	// A new job resets the per job time but not the totals.
+	clock = clock.Add(10 * time.Second)
+	report("RUNNING", 4, 166, "benchy.gcode")
+
+	if value := testutil.CollectAndCount(exporter.jobSpeedLevelSecondsMetric); value != 0 {
+		t.Errorf("Expected per job speed time to be reset, got %d series", value)
+	}
+	if value := testutil.ToFloat64(exporter.speedLevelSecondsMetric.WithLabelValues("ludicrous")); value != 40 {
+		t.Errorf("Expected 40s at ludicrous speed, got %f", value)
+	}
+}
@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// uploadFailed reports whether an upload status describes a failed upload.
+func uploadFailed(status string) bool {
+	return strings.Contains(strings.ToLower(status), "fail")
+}
+
+// updateUpload exports the progress of files being sent to the printer and
+// counts failed uploads. The upload block is skipped when the report does not
+// include a status.
+func (e *Exporter) updateUpload(data BambuLabsX1C) {
+	upload := data.Print.Upload
+	if upload.Status == "" {
+		return
+	}
+
+	e.uploadProgressMetric.Set(upload.Progress.Float64())
+	e.uploadSizeMetric.Set(upload.FileSize.Float64())
+	e.uploadFinishedMetric.Set(upload.FinishSize.Float64())
+	e.uploadSpeedMetric.Set(upload.Speed.Float64())
+	e.uploadRemainingMetric.Set(upload.TimeRemaining.Float64())
+
+	e.uploadStatusMetric.Reset()
+	e.uploadStatusMetric.WithLabelValues(upload.Status).Set(1)
+
+	if uploadFailed(upload.Status) && upload.Status != e.uploadStatus {
+		// The message is free text, so it is logged instead of being a label.
+		fmt.Printf("Upload failed (trouble_id %s): %s\n", upload.TroubleID, upload.Message)
+		e.uploadFailuresMetric.WithLabelValues(upload.TroubleID).Inc()
+	}
+	e.uploadStatus = upload.Status
+}
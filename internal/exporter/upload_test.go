@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUploadMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"upload": {
+				"file_size": 2048000,
+				"finish_size": 512000,
+				"message": "",
+				"progress": 25,
+				"speed": 102400,
+				"status": "uploading",
+				"time_remaining": 15,
+				"trouble_id": ""
+			}
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	gauges := map[string]float64{
+		"progress":  testutil.ToFloat64(exporter.uploadProgressMetric),
+		"size":      testutil.ToFloat64(exporter.uploadSizeMetric),
+		"finished":  testutil.ToFloat64(exporter.uploadFinishedMetric),
+		"speed":     testutil.ToFloat64(exporter.uploadSpeedMetric),
+		"remaining": testutil.ToFloat64(exporter.uploadRemainingMetric),
+	}
+	expected := map[string]float64{
+		"progress":  25,
+		"size":      2048000,
+		"finished":  512000,
+		"speed":     102400,
+		"remaining": 15,
+	}
+	for name, value := range expected {
+		if gauges[name] != value {
+			t.Errorf("Expected upload %s %v, got %v", name, value, gauges[name])
+		}
+	}
+	if value := testutil.ToFloat64(exporter.uploadStatusMetric.WithLabelValues("uploading")); value != 1 {
+		t.Errorf("Expected upload status uploading, got %v", value)
+	}
+
+	failed := `{"print": {"command": "push_status", "upload": {"status": "failed", "message": "network timeout", "trouble_id": "T123"}}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(failed)})
+	// Repeated reports of the same failure are only counted once.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(failed)})
+
+	if value := testutil.ToFloat64(exporter.uploadFailuresMetric.WithLabelValues("T123")); value != 1 {
+		t.Errorf("Expected 1 failed upload, got %v", value)
+	}
+	if count := testutil.CollectAndCount(exporter.uploadStatusMetric); count != 1 {
+		t.Errorf("Expected a single upload status series, got %d", count)
+	}
+
+	// Reports without an upload block keep the previous values.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status"}}`)})
+	if value := testutil.ToFloat64(exporter.uploadStatusMetric.WithLabelValues("failed")); value != 1 {
+		t.Errorf("Expected upload status to stay failed, got %v", value)
+	}
+}
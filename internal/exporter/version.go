@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// updateModuleInfo replaces the module info series with the modules listed
+// in a get_version reply.
+func (e *Exporter) updateModuleInfo(data BambuLabsX1C) {
+	if data.Info.Result != "" && data.Info.Result != "success" {
+		return
+	}
+
+	e.moduleInfoMetric.Reset()
+	for _, module := range data.Info.Module {
+		if module.Name == "ota" && e.model == ModelUnknown {
+			if model := modelFromProjectName(module.ProjectName); model != ModelUnknown {
+				e.setModel(model)
+			}
+		}
+		e.moduleInfoMetric.With(prometheus.Labels{
+			"module": module.Name,
+			"sw_ver": module.SwVer,
+			"hw_ver": module.HwVer,
+			"sn":     module.Sn,
+		}).Set(1)
+	}
+}
+
+// updateUpgradeState exports the firmware upgrade state included in
+// push_status reports. Partial reports without upgrade_state leave the
+// metrics unchanged, and the pending updates are only replaced when the
+// report carries new_version_state.
+func (e *Exporter) updateUpgradeState(data BambuLabsX1C) {
+	upgrade := data.Print.UpgradeState
+
+	if upgrade.ErrCode.Present() {
+		e.upgradeErrorCodeMetric.Set(upgrade.ErrCode.Float64())
+	}
+	if upgrade.Progress.Present() {
+		e.upgradeProgressMetric.Set(upgrade.Progress.Float64())
+	}
+	if !upgrade.NewVersionState.Present() {
+		return
+	}
+
+	e.upgradeStateMetric.Set(upgrade.NewVersionState.Float64())
+	e.firmwareUpdateMetric.Reset()
+	for module, version := range map[string]string{
+		"ota": upgrade.OtaNewVersionNumber,
+		"ams": upgrade.AmsNewVersionNumber,
+		"ahb": upgrade.AhbNewVersionNumber,
+	} {
+		if version == "" {
+			continue
+		}
+		e.firmwareUpdateMetric.With(prometheus.Labels{"module": module, "version": version}).Set(1)
+	}
+}
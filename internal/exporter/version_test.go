@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConnectRequestsVersion(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+	client := &mockClient{}
+
+	exporter.buildConnectHandler()(client)
+
+	if len(client.published) != 1 {
+		t.Fatalf("Expected 1 published message, got %d", len(client.published))
+	}
+	if client.published[0].topic != "device/test123/request" {
+		t.Errorf("Expected topic 'device/test123/request', got '%s'", client.published[0].topic)
+	}
+
+	var request infoRequest
+	if err := json.Unmarshal(client.published[0].payload, &request); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+	if request.Info.Command != "get_version" {
+		t.Errorf("Expected command 'get_version', got '%s'", request.Info.Command)
+	}
+	if request.Info.SequenceID == "" {
+		t.Errorf("Expected a sequence id")
+	}
+}
+
+func TestVersionInfoMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	reply := `{
+		"info": {
+			"command": "get_version",
+			"sequence_id": "1",
+			"module": [
+				{"name": "ota", "project_name": "BL-P001", "sw_ver": "01.07.00.00", "hw_ver": "", "sn": "00M00A000000000"},
+				{"name": "mc", "sw_ver": "00.00.26.49", "hw_ver": "MC07", "sn": "00M00A000000001"},
+				{"name": "ams/0", "sw_ver": "00.00.06.40", "hw_ver": "AMS08", "sn": "00600A000000000"}
+			],
+			"result": "success",
+			"reason": ""
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+
+	body := scrape(t, exporter)
+	expected := []string{
+		`bambulabs_module_info{hw_ver="",module="ota",sn="00M00A000000000",sw_ver="01.07.00.00"} 1`,
+		`bambulabs_module_info{hw_ver="MC07",module="mc",sn="00M00A000000001",sw_ver="00.00.26.49"} 1`,
+		`bambulabs_module_info{hw_ver="AMS08",module="ams/0",sn="00600A000000000",sw_ver="00.00.06.40"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+
+	// A later reply replaces the previous series.
+	reply = `{"info": {"command": "get_version", "module": [{"name": "ota", "sw_ver": "01.08.00.00", "sn": "00M00A000000000"}], "result": "success"}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(reply)})
+
+	body = scrape(t, exporter)
+	if !strings.Contains(body, `bambulabs_module_info{hw_ver="",module="ota",sn="00M00A000000000",sw_ver="01.08.00.00"} 1`) {
+		t.Errorf("Expected updated ota module info")
+	}
+	if strings.Contains(body, `module="mc"`) {
+		t.Errorf("Expected stale module info to be removed")
+	}
+}
+
+func TestUpgradeStateMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"upgrade_state": {
+				"new_version_state": 1,
+				"ota_new_version_number": "01.08.00.00",
+				"ams_new_version_number": "",
+				"progress": "42",
+				"err_code": 0
+			}
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	body := scrape(t, exporter)
+	expected := []string{
+		"bambulabs_upgrade_new_version_state 1",
+		"bambulabs_upgrade_progress_percent 42",
+		"bambulabs_upgrade_error_code 0",
+		`bambulabs_firmware_update_info{module="ota",version="01.08.00.00"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+	if strings.Contains(body, `bambulabs_firmware_update_info{module="ams"`) {
+		t.Errorf("Expected no pending ams update")
+	}
+
+	// Partial reports without upgrade_state keep the pending update.
+	report = `{"print": {"command": "push_status", "layer_num": 3}}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	body = scrape(t, exporter)
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to still contain %q after a partial report", line)
+		}
+	}
+}
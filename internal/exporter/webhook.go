@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+)
+
+// webhook posts events to a URL, either as JSON or rendered with the
+// template from BAMBULABS_WEBHOOK_TEMPLATE.
+type webhook struct {
+	eventFilter
+	label       string
+	url         string
+	contentType string
+	template    *template.Template
+	client      *http.Client
+}
+
+// templateFuncs are the functions available to webhook templates in addition
+// to the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		payload, err := json.Marshal(v)
+		return string(payload), err
+	},
+}
+
+// newWebhooks returns a webhook for each configured URL.
+func newWebhooks(cfg Config) ([]notifier, error) {
+	var tmpl *template.Template
+	if cfg.WebhookTemplate != "" {
+		var err error
+		tmpl, err = template.New("webhook").Funcs(templateFuncs).Parse(cfg.WebhookTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing BAMBULABS_WEBHOOK_TEMPLATE: %w", err)
+		}
+	}
+
+	var webhooks []notifier
+	hosts := map[string]int{}
+	for _, rawURL := range cfg.WebhookURLs {
+		webhooks = append(webhooks, &webhook{
+			eventFilter: cfg.WebhookEvents,
+			label:       webhookLabel(rawURL, hosts),
+			url:         rawURL,
+			contentType: cfg.WebhookContentType,
+			template:    tmpl,
+			client:      &http.Client{},
+		})
+	}
+	return webhooks, nil
+}
+
+// webhookLabel names a webhook after its host, e.g. webhook:example.com, so
+// a failing endpoint can be told apart in metrics and logs. The path is left
+// out as it often holds a secret. Further webhooks on the same host are
+// numbered, e.g. webhook:example.com#2.
+func webhookLabel(rawURL string, hosts map[string]int) string {
+	host := "invalid"
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	hosts[host]++
+	if hosts[host] > 1 {
+		return fmt.Sprintf("webhook:%s#%d", host, hosts[host])
+	}
+	return "webhook:" + host
+}
+
+func (w *webhook) name() string {
+	return w.label
+}
+
+func (w *webhook) notify(ctx context.Context, event Event) error {
+	var body bytes.Buffer
+	if w.template != nil {
+		if err := w.template.Execute(&body, event); err != nil {
+			return permanentError{fmt.Errorf("rendering template: %w", err)}
+		}
+	} else if err := json.NewEncoder(&body).Encode(event); err != nil {
+		return permanentError{err}
+	}
+
+	return postNotification(ctx, w.client, w.url, w.contentType, body.Bytes(), nil)
+}
+
+// redactURLError strips the path and query from the URL of a *url.Error, as
+// errors are logged and the path often holds a secret, e.g. the token of a
+// Discord webhook or a Telegram bot.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	u, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil || u.Host == "" {
+		urlErr.URL = redacted
+		return err
+	}
+	urlErr.URL = u.Scheme + "://" + u.Host
+	if u.Path != "" || u.RawQuery != "" {
+		urlErr.URL += "/" + redacted
+	}
+	return err
+}
+
+// postNotification posts a payload with the given extra headers and checks
+// the response status. Client errors other than timeouts and rate limiting
+// are permanent.
+func postNotification(ctx context.Context, client *http.Client, target, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{redactURLError(err)}
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return redactURLError(err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused.
+	defer io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return permanentError{fmt.Errorf("unexpected status %s", resp.Status)}
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
@@ -0,0 +1,226 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// webhookReceiver is an httptest server that replies with the given statuses
+// in order, repeating the last one, and records the bodies it receives.
+func webhookReceiver(t *testing.T, statuses ...int) (*httptest.Server, chan *http.Request, chan []byte) {
+	t.Helper()
+
+	var calls atomic.Int32
+	requests := make(chan *http.Request, 16)
+	bodies := make(chan []byte, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+		call := int(calls.Add(1)) - 1
+		w.WriteHeader(statuses[min(call, len(statuses)-1)])
+	}))
+	t.Cleanup(server.Close)
+	return server, requests, bodies
+}
+
+func TestWebhookJSON(t *testing.T) {
+	server, requests, bodies := webhookReceiver(t, http.StatusOK)
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_WEBHOOK_URLS": server.URL})
+
+	for _, report := range []string{
+		`{"print": {"command": "push_status", "gcode_state": "RUNNING", "subtask_name": "benchy", "mc_percent": 100}}`,
+		`{"print": {"command": "push_status", "gcode_state": "FINISH"}}`,
+	} {
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+	}
+	exporter.deliveries.Wait()
+
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 webhook, got %d", len(bodies))
+	}
+	if contentType := (<-requests).Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", contentType)
+	}
+	var event Event
+	if err := json.Unmarshal(<-bodies, &event); err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+	if event.Type != EventJobFinished || event.Job != "benchy" || event.Progress != 100 || event.Serial != "test123" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	server, _, bodies := webhookReceiver(t, http.StatusOK)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_WEBHOOK_URLS":         server.URL,
+		"BAMBULABS_WEBHOOK_TEMPLATE":     `{"text": {{ json .Message }}, "progress": {{ .Progress }}}`,
+		"BAMBULABS_WEBHOOK_CONTENT_TYPE": "application/vnd.test+json",
+	})
+
+	exporter.emit(Event{Type: EventJobFailed, Message: `"benchy" failed`, Progress: 42})
+	exporter.deliveries.Wait()
+
+	if body := string(<-bodies); body != `{"text": "\"benchy\" failed", "progress": 42}` {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
+
+func TestWebhookInvalidTemplate(t *testing.T) {
+	if _, err := newWebhooks(Config{WebhookURLs: []string{"http://localhost"}, WebhookTemplate: "{{ .Missing"}); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
+
+func TestWebhookLabels(t *testing.T) {
+	webhooks, err := newWebhooks(Config{WebhookURLs: []string{
+		"https://example.com/hooks/secret",
+		"https://hooks.example.org:8443/printer",
+		"https://example.com/hooks/other",
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create webhooks: %v", err)
+	}
+
+	expected := []string{"webhook:example.com", "webhook:hooks.example.org:8443", "webhook:example.com#2"}
+	for i, w := range webhooks {
+		if name := w.name(); name != expected[i] {
+			t.Errorf("Expected webhook %d to be named %s, got %s", i, expected[i], name)
+		}
+	}
+}
+
+func TestWebhookErrorRedactsPath(t *testing.T) {
+	server, _, _ := webhookReceiver(t, http.StatusOK)
+	server.Close()
+
+	webhooks, err := newWebhooks(Config{WebhookURLs: []string{server.URL + "/hooks/secret?key=hidden"}})
+	if err != nil {
+		t.Fatalf("Failed to create webhooks: %v", err)
+	}
+
+	err = webhooks[0].notify(context.Background(), Event{Type: EventJobFinished})
+	if err == nil {
+		t.Fatal("Expected an error for a closed server")
+	}
+	if message := err.Error(); strings.Contains(message, "secret") || strings.Contains(message, "hidden") || !strings.Contains(message, server.URL+"/REDACTED") {
+		t.Errorf("Expected the path to be redacted, got %s", message)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	server, _, bodies := webhookReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_WEBHOOK_URLS":   server.URL,
+		"BAMBULABS_NOTIFY_BACKOFF": "1ms",
+	})
+
+	exporter.emit(Event{Type: EventJobFinished})
+	exporter.deliveries.Wait()
+
+	name := "webhook:" + server.Listener.Addr().String()
+	if len(bodies) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(bodies))
+	}
+	if value := testutil.ToFloat64(exporter.notificationsMetric.WithLabelValues(name, EventJobFinished, "retried")); value != 2 {
+		t.Errorf("Expected 2 retries, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.notificationsMetric.WithLabelValues(name, EventJobFinished, "sent")); value != 1 {
+		t.Errorf("Expected 1 sent notification, got %v", value)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{"retries exhausted", http.StatusInternalServerError, 3},
+		{"rejected", http.StatusBadRequest, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, bodies := webhookReceiver(t, tt.status)
+			exporter := newTestExporter(t, map[string]string{
+				"BAMBULABS_WEBHOOK_URLS":   server.URL,
+				"BAMBULABS_NOTIFY_RETRIES": "2",
+				"BAMBULABS_NOTIFY_BACKOFF": "1ms",
+			})
+
+			exporter.emit(Event{Type: EventHMSError})
+			exporter.deliveries.Wait()
+
+			if len(bodies) != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, len(bodies))
+			}
+			if value := testutil.ToFloat64(exporter.notificationsMetric.WithLabelValues("webhook:"+server.Listener.Addr().String(), EventHMSError, "failed")); value != 1 {
+				t.Errorf("Expected 1 failed notification, got %v", value)
+			}
+		})
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	server, _, bodies := webhookReceiver(t, http.StatusOK)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_WEBHOOK_URLS":   server.URL,
+		"BAMBULABS_WEBHOOK_EVENTS": "job_failed,hms_error",
+	})
+
+	for _, eventType := range []string{EventJobStarted, EventJobFailed, EventFilamentLow, EventHMSError} {
+		exporter.emit(Event{Type: eventType})
+	}
+	exporter.deliveries.Wait()
+
+	if len(bodies) != 2 {
+		t.Errorf("Expected 2 webhooks, got %d", len(bodies))
+	}
+}
+
+func TestStopNotifiers(t *testing.T) {
+	server, _, bodies := webhookReceiver(t, http.StatusServiceUnavailable, http.StatusOK)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_WEBHOOK_URLS":   server.URL,
+		"BAMBULABS_NOTIFY_BACKOFF": "20ms",
+	})
+
+	exporter.emit(Event{Type: EventJobFinished})
+	if !exporter.stopNotifiers(5 * time.Second) {
+		t.Fatal("Expected the queued event to be delivered")
+	}
+	if len(bodies) != 2 {
+		t.Errorf("Expected the event to be retried and delivered, got %d attempts", len(bodies))
+	}
+
+	// Events after stopping are counted but not queued.
+	exporter.emit(Event{Type: EventJobFailed})
+	exporter.deliveries.Wait()
+	if len(bodies) != 2 {
+		t.Errorf("Expected no deliveries after stopping, got %d attempts", len(bodies))
+	}
+}
+
+func TestStopNotifiersTimeout(t *testing.T) {
+	server, _, _ := webhookReceiver(t, http.StatusServiceUnavailable)
+	exporter := newTestExporter(t, map[string]string{
+		"BAMBULABS_WEBHOOK_URLS":   server.URL,
+		"BAMBULABS_NOTIFY_BACKOFF": "1h",
+	})
+
+	exporter.emit(Event{Type: EventJobFinished})
+	if exporter.stopNotifiers(50 * time.Millisecond) {
+		t.Error("Expected to give up on an event that keeps being retried")
+	}
+}
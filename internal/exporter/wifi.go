@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wifiSample is a Wi-Fi signal reading kept for the rolling windows.
+type wifiSample struct {
+	time   time.Time
+	signal float64
+}
+
+// parseWifiSignal parses a signal strength such as "-52dBm". ok is false
+// when the report does not include the signal.
+func parseWifiSignal(signal string) (dbm float64, ok bool, err error) {
+	signal = strings.TrimSpace(signal)
+	if signal == "" {
+		return 0, false, nil
+	}
+	if len(signal) >= 3 && strings.EqualFold(signal[len(signal)-3:], "dbm") {
+		signal = strings.TrimSpace(signal[:len(signal)-3])
+	}
+
+	dbm, err = strconv.ParseFloat(signal, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("parsing wifi signal %q: %w", signal, err)
+	}
+	return dbm, true, nil
+}
+
+// wifiQuality converts a signal strength to a quality percentage, mapping
+// -100 dBm and below to 0% and -50 dBm and above to 100%.
+func wifiQuality(dbm float64) float64 {
+	return min(max(2*(dbm+100), 0), 100)
+}
+
+// windowLabel formats a window duration without zero units, e.g. 1h instead
+// of 1h0m0s.
+func windowLabel(window time.Duration) string {
+	label := window.String()
+	if strings.HasSuffix(label, "m0s") {
+		label = strings.TrimSuffix(label, "0s")
+	}
+	if strings.HasSuffix(label, "h0m") {
+		label = strings.TrimSuffix(label, "0m")
+	}
+	return label
+}
+
+// updateWifi exports the Wi-Fi signal, its quality and the minimum and
+// average signal over each configured window.
+func (e *Exporter) updateWifi(data BambuLabsX1C) {
+	signal, ok, err := parseWifiSignal(data.Print.WifiSignal)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		e.wifiSignalErrorsMetric.Inc()
+		return
+	}
+	if !ok {
+		return
+	}
+
+	e.wifiSignalMetric.Set(signal)
+	e.wifiQualityMetric.Set(wifiQuality(signal))
+
+	now := e.now()
+	e.wifiSamples = append(e.wifiSamples, wifiSample{time: now, signal: signal})
+
+	var longest time.Duration
+	for _, window := range e.config.WifiWindows {
+		longest = max(longest, window)
+	}
+	for len(e.wifiSamples) > 0 && now.Sub(e.wifiSamples[0].time) > longest {
+		e.wifiSamples = e.wifiSamples[1:]
+	}
+
+	for _, window := range e.config.WifiWindows {
+		lowest, sum, count := 0.0, 0.0, 0
+		for _, sample := range e.wifiSamples {
+			if now.Sub(sample.time) > window {
+				continue
+			}
+			if count == 0 || sample.signal < lowest {
+				lowest = sample.signal
+			}
+			sum += sample.signal
+			count++
+		}
+
+		label := windowLabel(window)
+		e.wifiSignalMinMetric.WithLabelValues(label).Set(lowest)
+		e.wifiSignalAvgMetric.WithLabelValues(label).Set(sum / float64(count))
+	}
+}
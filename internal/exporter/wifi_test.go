@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseWifiSignal(t *testing.T) {
+	tests := []struct {
+		signal   string
+		expected float64
+		ok       bool
+		err      bool
+	}{
+		{"-52dBm", -52, true, false},
+		{"-52 dBm", -52, true, false},
+		{"-52DBM", -52, true, false},
+		{"-52", -52, true, false},
+		{" -61dBm ", -61, true, false},
+		{"", 0, false, false},
+		{"weak", 0, false, true},
+		{"dBm", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.signal, func(t *testing.T) {
+			dbm, ok, err := parseWifiSignal(tt.signal)
+			if (err != nil) != tt.err {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if dbm != tt.expected || ok != tt.ok {
+				t.Errorf("Expected %v, %v, got %v, %v", tt.expected, tt.ok, dbm, ok)
+			}
+		})
+	}
+}
+
+func TestWifiQuality(t *testing.T) {
+	tests := []struct {
+		dbm      float64
+		expected float64
+	}{
+		{-110, 0},
+		{-100, 0},
+		{-75, 50},
+		{-50, 100},
+		{-30, 100},
+	}
+
+	for _, tt := range tests {
+		if quality := wifiQuality(tt.dbm); quality != tt.expected {
+			t.Errorf("wifiQuality(%v) = %v, expected %v", tt.dbm, quality, tt.expected)
+		}
+	}
+}
+
+func TestWindowLabel(t *testing.T) {
+	tests := []struct {
+		window   time.Duration
+		expected string
+	}{
+		{30 * time.Second, "30s"},
+		{5 * time.Minute, "5m"},
+		{90 * time.Second, "1m30s"},
+		{time.Hour, "1h"},
+		{24 * time.Hour, "24h"},
+		{time.Hour + 30*time.Minute, "1h30m"},
+	}
+
+	for _, tt := range tests {
+		if label := windowLabel(tt.window); label != tt.expected {
+			t.Errorf("windowLabel(%v) = %s, expected %s", tt.window, label, tt.expected)
+		}
+	}
+}
+
+func TestWifiMetrics(t *testing.T) {
+	exporter := newTestExporter(t, map[string]string{"BAMBULABS_WIFI_WINDOWS": "1m,10m"})
+
+	clock := time.Unix(1700000000, 0)
+	exporter.now = func() time.Time { return clock }
+
+	report := func(signal string) {
+		t.Helper()
+		payload := fmt.Sprintf(`{"print": {"command": "push_status", "wifi_signal": %q}}`, signal)
+		exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(payload)})
+	}
+
+	report("-80dBm")
+	clock = clock.Add(5 * time.Minute)
+	report("-60dBm")
+	clock = clock.Add(30 * time.Second)
+	report("-50dBm")
+
+	if value := testutil.ToFloat64(exporter.wifiSignalMetric); value != -50 {
+		t.Errorf("Expected wifi signal -50, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.wifiQualityMetric); value != 100 {
+		t.Errorf("Expected wifi quality 100, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.wifiSignalMinMetric.WithLabelValues("1m")); value != -60 {
+		t.Errorf("Expected 1m minimum -60, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.wifiSignalAvgMetric.WithLabelValues("1m")); value != -55 {
+		t.Errorf("Expected 1m average -55, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.wifiSignalMinMetric.WithLabelValues("10m")); value != -80 {
+		t.Errorf("Expected 10m minimum -80, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.wifiSignalAvgMetric.WithLabelValues("10m")); value != -63.333333333333336 {
+		t.Errorf("Expected 10m average -63.3, got %v", value)
+	}
+
+	// Samples older than the longest window are dropped.
+	clock = clock.Add(11 * time.Minute)
+	report("-70dBm")
+	if len(exporter.wifiSamples) != 1 {
+		t.Errorf("Expected 1 sample to be kept, got %d", len(exporter.wifiSamples))
+	}
+
+	// Invalid values are counted and do not change the signal.
+	report("unknown")
+	if value := testutil.ToFloat64(exporter.wifiSignalErrorsMetric); value != 1 {
+		t.Errorf("Expected 1 parse error, got %v", value)
+	}
+	if value := testutil.ToFloat64(exporter.wifiSignalMetric); value != -70 {
+		t.Errorf("Expected wifi signal to stay -70, got %v", value)
+	}
+}
+
+func TestInvalidWifiWindows(t *testing.T) {
+	for _, windows := range []string{"0s", "5m,-1h"} {
+		t.Run(windows, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected NewExporter to refuse windows %s", windows)
+				}
+			}()
+			newTestExporter(t, map[string]string{"BAMBULABS_WIFI_WINDOWS": windows})
+		})
+	}
+}
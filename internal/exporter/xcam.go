@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"fmt"
+)
+
+// xcamSensitivities maps halt_print_sensitivity to a number so alerts can
+// compare against a minimum sensitivity.
+var xcamSensitivities = map[string]float64{
+	"low":    1,
+	"medium": 2,
+	"high":   3,
+}
+
+// xcamModule is the module id in the upper 16 bits of print_error for
+// errors raised by the X-Cam AI detectors.
+const xcamModule = 0x0C00
+
+// isXcamError reports whether a print_error code was raised by the X-Cam.
+func isXcamError(code int) bool {
+	return uint32(code)>>16 == xcamModule
+}
+
+// updateXcam exports the X-Cam detector settings and counts prints halted by
+// the X-Cam. A halt is a transition from RUNNING to PAUSE or FAILED with an
+// X-Cam print_error. The xcam block is only present in full reports, which
+// always include halt_print_sensitivity.
+func (e *Exporter) updateXcam(data BambuLabsX1C) {
+	xcam := data.Print.Xcam
+
+	if xcam.HaltPrintSensitivity != "" {
+		for detector, enabled := range map[string]bool{
+			"spaghetti_detector":         xcam.SpaghettiDetector,
+			"first_layer_inspector":      xcam.FirstLayerInspector,
+			"buildplate_marker_detector": xcam.BuildplateMarkerDetector,
+			"printing_monitor":           xcam.PrintingMonitor,
+			"print_halt":                 xcam.PrintHalt,
+			"allow_skip_parts":           xcam.AllowSkipParts,
+		} {
+			value := 0.0
+			if enabled {
+				value = 1
+			}
+			e.xcamDetectorMetric.WithLabelValues(detector).Set(value)
+		}
+
+		if sensitivity, ok := xcamSensitivities[xcam.HaltPrintSensitivity]; ok {
+			e.xcamSensitivityMetric.Set(sensitivity)
+		}
+	}
+
+	if data.Print.XcamStatus.Present() {
+		e.xcamStatusMetric.Set(data.Print.XcamStatus.Float64())
+	}
+
+	state := data.Print.GcodeState
+	if e.gcodeState == "RUNNING" && (state == "PAUSE" || state == "FAILED") && isXcamError(data.Print.PrintError.Int()) {
+		e.xcamHaltsMetric.WithLabelValues(fmt.Sprintf("%08X", uint32(data.Print.PrintError.Int()))).Inc()
+	}
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestIsXcamError(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected bool
+	}{
+		{0, false},
+		{0x0C008001, true},
+		{0x0C003003, true},
+		{0x0300800A, false},
+		{0x07008011, false},
+	}
+
+	for _, tt := range tests {
+		if isXcamError(tt.code) != tt.expected {
+			t.Errorf("isXcamError(%08X) = %v, expected %v", tt.code, !tt.expected, tt.expected)
+		}
+	}
+}
+
+func TestXcamMetrics(t *testing.T) {
+	exporter := newTestExporter(t, nil)
+
+	report := `{
+		"print": {
+			"command": "push_status",
+			"gcode_state": "RUNNING",
+			"xcam_status": "0",
+			"xcam": {
+				"allow_skip_parts": false,
+				"buildplate_marker_detector": true,
+				"first_layer_inspector": true,
+				"halt_print_sensitivity": "medium",
+				"print_halt": true,
+				"printing_monitor": true,
+				"spaghetti_detector": false
+			}
+		}
+	}`
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(report)})
+
+	detectors := map[string]float64{
+		"spaghetti_detector":         0,
+		"first_layer_inspector":      1,
+		"buildplate_marker_detector": 1,
+		"printing_monitor":           1,
+		"print_halt":                 1,
+		"allow_skip_parts":           0,
+	}
+	for detector, expected := range detectors {
+		if value := testutil.ToFloat64(exporter.xcamDetectorMetric.WithLabelValues(detector)); value != expected {
+			t.Errorf("Expected %s %v, got %v", detector, expected, value)
+		}
+	}
+	if value := testutil.ToFloat64(exporter.xcamSensitivityMetric); value != 2 {
+		t.Errorf("Expected halt sensitivity 2, got %v", value)
+	}
+
+	// Partial reports without the xcam block keep the previous settings.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status"}}`)})
+	if value := testutil.ToFloat64(exporter.xcamDetectorMetric.WithLabelValues("first_layer_inspector")); value != 1 {
+		t.Errorf("Expected first_layer_inspector to stay enabled, got %v", value)
+	}
+
+	// A pause with an X-Cam error counts as a halt.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status", "gcode_state": "PAUSE", "print_error": 201359361}}`)})
+	// Further reports in the paused state do not.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status", "gcode_state": "PAUSE", "print_error": 201359361}}`)})
+	// A pause for another reason does not.
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status", "gcode_state": "RUNNING"}}`)})
+	exporter.messagePubHandler(&mockClient{}, &mockMessage{payload: []byte(`{"print": {"command": "push_status", "gcode_state": "PAUSE", "print_error": 0}}`)})
+
+	if value := testutil.ToFloat64(exporter.xcamHaltsMetric.WithLabelValues("0C008001")); value != 1 {
+		t.Errorf("Expected 1 X-Cam halt, got %v", value)
+	}
+	if count := testutil.CollectAndCount(exporter.xcamHaltsMetric); count != 1 {
+		t.Errorf("Expected a single halt series, got %d", count)
+	}
+}
@@ -1,29 +1,124 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
-
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/collectors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/halkeye/bambulabs-exporter/internal/exporter"
 )
 
 func main() {
-	// Unregister default collectors
-	prometheus.Unregister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
-	prometheus.Unregister(collectors.NewGoCollector())
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		simulate(os.Args[2:])
+		return
+	}
 
 	// Create and start the exporter
 	exp := exporter.NewExporter()
-	
+
+	// Connect to the Home Assistant broker, if configured, before reports
+	// start arriving
+	exp.ConnectToHomeAssistant()
+
 	// Connect to MQTT broker
 	exp.ConnectToBroker()
-	
+
+	// Share the printer connection with other MQTT clients, if configured
+	exp.StartProxy()
+
+	// Push metrics to an OTLP collector, if configured
+	exp.StartOTLP()
+
+	// Push metrics to a remote write or InfluxDB endpoint, if configured
+	exp.StartPush()
+
 	// Start HTTP server
 	exp.StartHTTPServer()
-	
+
 	// Start the HTTP server
+	go func() {
+		log.Fatal(http.ListenAndServe(":9101", nil))
+	}()
+
+	// Deliver queued notifications and push the last metrics to the OTLP
+	// collector before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	exp.StopNotifiers()
+	exp.StopOTLP()
+}
+
+// replay serves the metrics of a recording made with BAMBULABS_RECORD_FILE
+// instead of connecting to a printer.
+func replay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := flags.Float64("speed", 1, "replay speed, e.g. 10 for ten times as fast or 0 for as fast as possible")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: bambulabs-exporter replay [-speed N] recording.jsonl")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	exp := exporter.NewExporter()
+	exp.StartHTTPServer()
+
+	go func() {
+		if err := exp.Replay(flags.Arg(0), *speed); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Replay of %s finished\n", flags.Arg(0))
+	}()
+
 	log.Fatal(http.ListenAndServe(":9101", nil))
-}
\ No newline at end of file
+}
+
+// simulate emulates a printer on a local MQTT broker to test the exporter,
+// dashboards and alert rules without hardware.
+func simulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	address := flags.String("address", ":8883", "address the MQTT broker listens on")
+	model := flags.String("model", "X1C", "printer model to simulate")
+	serial := flags.String("serial", "", "serial number, defaults to one matching the model")
+	accessCode := flags.String("access-code", "12345678", "access code clients log in with as bblp")
+	interval := flags.Duration("interval", time.Second, "time between reports")
+	layers := flags.Int("layers", 50, "layers per print job")
+	scenario := flags.String("scenario", "cycle", "cycle alternates finished and failed prints, finish and failure only do one")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: bambulabs-exporter simulate [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	simulator, err := exporter.NewSimulator(exporter.SimulatorConfig{
+		Address:    *address,
+		Model:      exporter.ParsePrinterModel(*model),
+		Serial:     *serial,
+		AccessCode: *accessCode,
+		Interval:   *interval,
+		Layers:     *layers,
+		Scenario:   *scenario,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Simulating %s %s on %s\n", *model, simulator.Serial(), simulator.Address())
+	fmt.Printf("Point the exporter at it with BAMBULABS_IP, BAMBULABS_PORT, BAMBULABS_USERNAME=bblp, BAMBULABS_PASSWORD=%s and BAMBULABS_TOPIC=device/%s/report\n", *accessCode, simulator.Serial())
+	simulator.Run()
+}